@@ -4,30 +4,14 @@
 package main
 
 import (
-	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"math/rand"
+	"os"
 	"time"
-)
-
-var ErrFatalSocketError = errors.New("fatal socket error")
-
-type ConnectCloser interface {
-	Connect() (Reader, error)
-	Close() error
-}
-
-// STARTREADER OMIT
-type Reader interface {
-	Read() (*Message, error)
-}
 
-// STOPREADER OMIT
-
-type Message struct {
-	Content string
-}
+	"github.com/mstreet3/go-blogs/supervise"
+)
 
 // STARTEVENTUALLYFATALREADER OMIT
 type eventuallyFatal struct {
@@ -36,96 +20,65 @@ type eventuallyFatal struct {
 
 // eventuallyFatal will eventally be stuck in a state of only returning // HL
 // ErrFatalSocketError. // HL
-func (eveFatal *eventuallyFatal) Read() (*Message, error) {
+func (eveFatal *eventuallyFatal) Read() (*supervise.Message, error) {
 	if eveFatal.err != nil { // HL
 		return nil, eveFatal.err // HL
 	}
 
 	if rand.Intn(4) == 3 {
-		eveFatal.err = ErrFatalSocketError
+		eveFatal.err = supervise.ErrFatalSocketError
 		return nil, eveFatal.err
 	}
 
-	return &Message{
+	return &supervise.Message{
 		Content: fmt.Sprintf("%d", rand.Int()),
 	}, nil
 }
 
 // STOPEVENTUALLYFATALREADER OMIT
 
-type eventuallyFatalConnection struct{}
-
-func (conn *eventuallyFatalConnection) Connect() (Reader, error) {
-	log.Println("conn: connected successfully")
-	return &eventuallyFatal{}, nil
+type eventuallyFatalConnection struct {
+	logger *slog.Logger
 }
 
-func (conn *eventuallyFatalConnection) Close() error {
-	log.Println("conn: disconnected successfully")
-	return nil
+func (conn *eventuallyFatalConnection) Connect() (supervise.Conn, error) {
+	conn.logger.Info("connected successfully")
+	return &eventuallyFatalConn{logger: conn.logger}, nil
 }
 
-// STARTREADERWARD OMIT
-func readerWard(
-	stop <-chan struct{}, conn Reader, pulseInterval time.Duration,
-) (<-chan struct{}, <-chan error) {
-
-	done := make(chan struct{}) // <1> // HL
-	errs := make(chan error, 1) // <2> // HL
-	ticker := time.NewTicker(pulseInterval)
-
-	cleanup := func() {
-		ticker.Stop()
-		close(errs)
-		close(done)
-	}
-
-	sendErr := func(e error) { // <5> // HL
-		select {
-		case <-stop:
-			return
-		case errs <- e:
-		default:
-			log.Println("ward: no error listeners")
-		}
-	}
-
-	go func() {
-		defer cleanup() // <3> // HL
-
-		for {
-			select {
-			case <-stop:
-				return
-			case <-ticker.C:
-				msg, err := conn.Read() // <4> // HL
-
-				if err != nil {
-					sendErr(err)
-					continue
-				}
-
-				log.Printf("ward: read message %s", msg.Content)
-			}
-		}
-	}()
+// eventuallyFatalConn is a connection that reads from an eventuallyFatal
+// reader.
+type eventuallyFatalConn struct {
+	eventuallyFatal
+	logger *slog.Logger
+}
 
-	return done, errs // <6> // HL
+func (conn *eventuallyFatalConn) Close() error {
+	conn.logger.Info("disconnected successfully")
+	return nil
 }
 
-// STOPREADERWARD OMIT
+// newLogger returns a logger that writes every record to stderr.
+func newLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+	}))
+}
 
 // STARTMAINLIVELOCK OMIT
 func main() {
 	stop := make(chan struct{})
-	reader, _ := new(eventuallyFatalConnection).Connect()
+	logger := newLogger()
+	network := &eventuallyFatalConnection{logger: logger.With("worker", "conn")}
+	reader, _ := network.Connect()
 	pulseInterval := 300 * time.Millisecond
 
-	done, errs := readerWard(stop, reader, pulseInterval) // <1> // HL
+	done, errs := supervise.ReaderWard(stop, reader, pulseInterval, // <1> // HL
+		supervise.WithLogger(logger))
 
 	go func() {
 		for e := range errs {
-			log.Printf("main: read error %v\n", e) // <2> // HL
+			logger.Error("read error", "worker", "main", "err", e) // <2> // HL
 		}
 	}()
 
@@ -0,0 +1,259 @@
+//go:build ignore && OMIT
+// +build ignore,OMIT
+
+// The ward, monitor and steward shown in livelockrecover.article.  They are
+// pared down from the workers of the supervise package, which the article's
+// programs run.
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/mstreet3/go-blogs/supervise"
+)
+
+type Message = supervise.Message
+
+// errStopped is the cause given to a ward when its steward is stopped.
+var errStopped = errors.New("steward: stopped")
+
+// STARTREADER OMIT
+type Reader interface {
+	Read() (*Message, error)
+}
+
+// STOPREADER OMIT
+
+// STARTREADERWARD OMIT
+// readerWard reads from conn on every tick and forwards any read errors on // HL
+// its returned error channel. // HL
+func readerWard(
+	ctx context.Context, conn Reader, pulseInterval time.Duration,
+	heartbeat chan<- time.Time, logger *slog.Logger,
+) (<-chan struct{}, <-chan error) {
+
+	stop := ctx.Done()
+	done := make(chan struct{}) // <1> // HL
+	errs := make(chan error, 1) // <2> // HL
+	ticker := time.NewTicker(pulseInterval)
+
+	cleanup := func() {
+		ticker.Stop()
+
+		// Tell any listener why the ward was stopped.
+		if cause := context.Cause(ctx); !errors.Is(cause, context.Canceled) {
+			select {
+			case errs <- cause:
+			default:
+			}
+		}
+
+		close(errs)
+		close(done)
+	}
+
+	sendErr := func(e error) { // <5> // HL
+		select {
+		case <-stop:
+			return
+		case errs <- e:
+		default:
+			logger.Warn("no error listeners", "err", e)
+		}
+	}
+
+	go func() {
+		defer cleanup() // <3> // HL
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				msg, err := conn.Read() // <4> // HL
+				if err != nil {
+					sendErr(err)
+					continue
+				}
+
+				logger.Debug("read message", "content", msg.Content)
+
+				// Signal that the ward is alive without blocking.
+				select {
+				case heartbeat <- time.Now():
+				default:
+				}
+			}
+		}
+	}()
+
+	return done, errs // <6> // HL
+}
+
+// STOPREADERWARD OMIT
+
+// STARTMONITOR OMIT
+// monitor is a routine with the single responsibility of sending the reason // HL
+// the ward is unhealthy on its returned channel, and closing it, if its // HL
+// policy judges the ward unhealthy or the ward misses its heartbeat. // HL
+func monitor(
+	ctx context.Context, errs <-chan error, heartbeat <-chan time.Time,
+	timeout time.Duration, policy supervise.HealthPolicy, logger *slog.Logger,
+) <-chan error {
+
+	unhealthy := make(chan error, 1)
+	timer := time.NewTimer(timeout)
+
+	go func() {
+		defer close(unhealthy)
+		defer timer.Stop()
+		defer logger.Debug("shutting down")
+
+		policy.Reset()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case at := <-heartbeat:
+				if !timer.Stop() {
+					<-timer.C
+				}
+				timer.Reset(timeout)
+				policy.Success(at)
+			case <-timer.C: // <3> // HL
+				err := fmt.Errorf("no heartbeat within %v", timeout)
+				logger.Warn("ward is unhealthy", "err", err)
+				unhealthy <- err
+				return
+			case e, ok := <-errs: // <1> // HL
+				if !ok {
+					return
+				}
+
+				if policy.Failure(time.Now(), e) { // <2> // HL
+					logger.Warn("ward is unhealthy", "err", e)
+					unhealthy <- e
+					return
+				}
+			}
+		}
+	}()
+
+	return unhealthy
+}
+
+// STOPMONITOR OMIT
+
+// STARTSTEWARD OMIT
+// connectionSteward connects to network, starts a ward on the connection and // HL
+// restarts the ward on a new connection whenever it becomes unhealthy until // HL
+// ctx is done.  Failures to close a connection are sent on its errors. // HL
+func connectionSteward(
+	ctx context.Context, network supervise.Connector,
+	pulseInterval time.Duration, health supervise.HealthPolicy,
+	logger *slog.Logger,
+) (<-chan struct{}, <-chan error) {
+
+	// Define channels that other clients may consume. <1> // HL
+	done := make(chan struct{})
+	errs := make(chan error, 1)
+
+	// Define a function to send errors in a non-blocking fashion. // HL
+	sendErr := func(e error) {
+		select {
+		case <-ctx.Done():
+		case errs <- e:
+		default:
+			logger.Warn("no error listeners", "err", e)
+		}
+	}
+
+	// health contains the business logic for when to trigger a ward // HL
+	// restart. // HL
+	if health == nil {
+		health = supervise.ForClass(supervise.ErrFatalSocketError, // <2> // HL
+			supervise.ConsecutiveErrors(1))
+	}
+
+	go func() {
+		// Cleanup and close the owned channels. // HL
+		defer func() {
+			close(errs)
+			close(done)
+		}()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			default:
+				// Attempt to connect to the network.
+				conn, err := network.Connect() // <3> // HL
+				if err != nil {
+					logger.Warn("failed to connect", "err", err)
+					sendErr(err)
+
+					// Wait for pulseInterval duration of time
+					// to pass before retrying to connect.
+					select {
+					case <-ctx.Done():
+					case <-time.After(pulseInterval):
+					}
+					continue
+				}
+
+				// Start a new ward to read from the connection.
+				wardCtx, stopWard := context.WithCancelCause(ctx)
+				beats := make(chan time.Time, 1)
+				reading, readerErrs := readerWard(wardCtx, conn, // <4> // HL
+					pulseInterval/2, beats, logger.With("worker", "ward"))
+
+				// Monitor the ward's health.
+				restart := monitor(wardCtx, readerErrs, beats, // <5> // HL
+					10*pulseInterval, health, logger.With("worker", "monitor"))
+
+				// Wait for the signal to restart or to stop
+				// completely.
+				select { // <6> // HL
+				case <-ctx.Done():
+					logger.Info("received shutdown signal; stopping ward")
+					stopWard(errStopped)
+				case cause := <-restart:
+					logger.Info("stopping unhealthy ward", "cause", cause)
+					stopWard(cause)
+				}
+
+				// Cleanup the ward and connection. // <7> // HL
+				<-reading
+				if err := closeWithin(conn, 10*pulseInterval); err != nil {
+					sendErr(err)
+				}
+			}
+		}
+	}()
+
+	return done, errs
+}
+
+// STOPSTEWARD OMIT
+
+// closeWithin closes conn and reports an error if closing fails or takes
+// longer than timeout, leaving a close that hangs behind.
+func closeWithin(conn supervise.Conn, timeout time.Duration) error {
+	closed := make(chan error, 1)
+	go func() {
+		closed <- conn.Close()
+	}()
+
+	select {
+	case err := <-closed:
+		return err
+	case <-time.After(timeout):
+		return fmt.Errorf("close timed out after %v", timeout)
+	}
+}
@@ -5,8 +5,9 @@ package main
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
 	"math/rand"
+	"os"
 	"strings"
 	"time"
 )
@@ -99,9 +100,20 @@ func getComponents() Components {
 	return arr[index]
 }
 
+// Clock measures the signaler's delay, so that a test can replace it with a
+// fake clock instead of sleeping.
+type Clock interface {
+	Sleep(d time.Duration)
+}
+
+// systemClock sleeps in real time.
+type systemClock struct{}
+
+func (systemClock) Sleep(d time.Duration) { time.Sleep(d) }
+
 // STARTAGENT OMIT
 func agent(
-	stop <-chan struct{}, signal <-chan struct{},
+	stop <-chan struct{}, signal <-chan struct{}, logger *slog.Logger,
 ) (<-chan struct{}, AvailableComponents) {
 
 	var (
@@ -123,7 +135,7 @@ func agent(
 		close(tobaccoCh)
 		close(papersCh)
 		close(lighterCh)
-		log.Println("agent: shutdown complete")
+		logger.Info("shutdown complete")
 	}
 
 	go func() {
@@ -143,25 +155,25 @@ func agent(
 						case <-stop: // <5> // HL
 							return
 						case tobaccoCh <- Tobacco{}:
-							log.Printf("agent: sent %v\n", TobaccoComponent)
+							logger.Info("sent component", "component", TobaccoComponent)
 						}
 					case PapersComponent:
 						select {
 						case <-stop:
 							return
 						case papersCh <- Papers{}:
-							log.Printf("agent: sent %v\n", PapersComponent)
+							logger.Info("sent component", "component", PapersComponent)
 						}
 					case LighterComponent:
 						select {
 						case <-stop:
 							return
 						case lighterCh <- Lighter{}:
-							log.Printf("agent: sent %v\n", LighterComponent)
+							logger.Info("sent component", "component", LighterComponent)
 						}
 					default:
-						log.Fatalf("agent: received unknown value %d", c)
-						return
+						logger.Error("received unknown component", "component", int(c))
+						os.Exit(1)
 					}
 				}
 			}
@@ -176,7 +188,7 @@ func agent(
 
 // STARTTABLESIG OMIT
 func table(
-	stop <-chan struct{}, ac AvailableComponents,
+	stop <-chan struct{}, ac AvailableComponents, logger *slog.Logger,
 ) (<-chan struct{}, SmokerWithTobacco, SmokerWithPapers, SmokerWithLighter) {
 	// STOPTABLESIG OMIT
 
@@ -210,7 +222,7 @@ func table(
 	cleanup := func() {
 		defer close(done)
 
-		log.Println("table: shutdown complete")
+		logger.Info("shutdown complete")
 	}
 
 	go func() {
@@ -300,6 +312,7 @@ func hasLighter(
 	stop <-chan struct{},
 	tobaccoCh <-chan Tobacco,
 	papersCh <-chan Papers,
+	logger *slog.Logger,
 ) (<-chan struct{}, <-chan string) {
 
 	var (
@@ -311,7 +324,7 @@ func hasLighter(
 		defer close(done)
 
 		close(messages)
-		log.Println("hasLighter: shutdown complete")
+		logger.Info("shutdown complete")
 	}
 
 	go func() {
@@ -349,6 +362,7 @@ func hasPapers(
 	stop <-chan struct{},
 	tobaccoCh <-chan Tobacco,
 	lighterCh <-chan Lighter,
+	logger *slog.Logger,
 ) (<-chan struct{}, <-chan string) {
 
 	var (
@@ -360,7 +374,7 @@ func hasPapers(
 		defer close(done)
 
 		close(messages)
-		log.Println("hasPapers: shutdown complete")
+		logger.Info("shutdown complete")
 	}
 
 	go func() {
@@ -399,6 +413,7 @@ func hasTobacco(
 	stop <-chan struct{},
 	papersCh <-chan Papers,
 	lighterCh <-chan Lighter,
+	logger *slog.Logger,
 ) (<-chan struct{}, <-chan string) {
 
 	var (
@@ -410,7 +425,7 @@ func hasTobacco(
 		defer close(done)
 
 		close(messages)
-		log.Println("hasTobacco: shutdown complete")
+		logger.Info("shutdown complete")
 	}
 
 	go func() {
@@ -447,7 +462,10 @@ func hasTobacco(
 // STOPHASTOBACCO OMIT
 
 // STARTSIGNALERSIG OMIT
-func signaler(stop <-chan struct{}, delay time.Duration) <-chan struct{} {
+func signaler(
+	stop <-chan struct{}, delay time.Duration, logger *slog.Logger,
+	clock Clock,
+) <-chan struct{} {
 	// STOPSIGNALERSIG OMIT
 
 	// STARTSIGNALERSETUP OMIT
@@ -456,23 +474,27 @@ func signaler(stop <-chan struct{}, delay time.Duration) <-chan struct{} {
 		signal = make(chan struct{})
 
 		// Start the agent goroutine.
-		agentDone, forTable = agent(stop, signal)
+		agentDone, forTable = agent(stop, signal,
+			logger.With("worker", "agent"))
 
 		// Start gathering supplies on the table and passing them to
 		// smokers.
-		tableDone, forSWT, forSWP, forSWL = table(stop, forTable)
+		tableDone, forSWT, forSWP, forSWL = table(stop, forTable,
+			logger.With("worker", "table"))
 
 		// Start each of the smokers.
 		tobaccoDone, tobaccoMsgs = hasTobacco(stop, forSWT.Papers,
-			forSWT.Lighter)
+			forSWT.Lighter, logger.With("worker", "hasTobacco"))
 
 		papersDone, papersMsgs = hasPapers(stop, forSWP.Tobacco,
-			forSWP.Lighter)
+			forSWP.Lighter, logger.With("worker", "hasPapers"))
 
 		lighterDone, lighterMsgs = hasLighter(stop, forSWL.Tobacco,
-			forSWL.Papers)
+			forSWL.Papers, logger.With("worker", "hasLighter"))
 	)
 
+	logger = logger.With("worker", "signaler")
+
 	// STOPSIGNALERSETUP OMIT
 
 	// STARTWAIT OMIT
@@ -492,10 +514,10 @@ func signaler(stop <-chan struct{}, delay time.Duration) <-chan struct{} {
 	// a message from a smoker.
 	go func() {
 		defer close(done)
-		defer log.Println("signaler: shutdown complete")
+		defer logger.Info("shutdown complete")
 		defer close(signal)
 		defer wait() // <1> // HL
-		defer log.Println("signaler: starting shutdown")
+		defer logger.Info("starting shutdown")
 
 		// Initialize the agent by sending a signal.
 		signal <- struct{}{} // <2> // HL
@@ -508,20 +530,20 @@ func signaler(stop <-chan struct{}, delay time.Duration) <-chan struct{} {
 				if !ok {
 					return
 				}
-				log.Println(msg)
+				logger.Info(msg)
 			case msg, ok := <-papersMsgs:
 				if !ok {
 					return
 				}
-				log.Println(msg)
+				logger.Info(msg)
 			case msg, ok := <-lighterMsgs:
 				if !ok {
 					return
 				}
-				log.Println(msg)
+				logger.Info(msg)
 			}
 
-			time.Sleep(delay) // <3> // HL
+			clock.Sleep(delay) // <3> // HL
 
 			select {
 			case <-stop:
@@ -541,15 +563,16 @@ func main() {
 		stop        = make(chan struct{})
 		timeout     = 3 * time.Second
 		delay       = timeout / 9
-		isSignaling = signaler(stop, delay)
+		logger      = slog.New(slog.NewTextHandler(os.Stderr, nil))
+		isSignaling = signaler(stop, delay, logger, systemClock{})
 	)
 
 	time.AfterFunc(timeout, func() {
-		log.Println("main: shutting down")
+		logger.Info("shutting down", "worker", "main")
 		close(stop)
 	})
 
-	defer log.Println("main: shutdown complete")
+	defer logger.Info("shutdown complete", "worker", "main")
 	<-isSignaling
 }
 
@@ -1,6 +1,6 @@
 module github.com/mstreet3/go-blogs
 
-go 1.19
+go 1.21
 
 require (
 	github.com/yuin/goldmark v1.4.13 // indirect
@@ -0,0 +1,218 @@
+package supervise
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Admin is an http.Handler that lets operators inspect and command the
+// stewards registered with it.  Mounted with http.StripPrefix, it serves
+//
+//	GET  /                list every steward and its status
+//	GET  /{name}          the status of a steward
+//	POST /{name}/pause    pause a steward
+//	POST /{name}/resume   resume a paused steward
+//	POST /{name}/restart  force a steward to restart its ward
+//	POST /{name}/stop     stop a steward
+//
+// Statuses are sent as JSON, and every command replies with the status of
+// the steward once the steward has obeyed it.  An Admin is safe for
+// concurrent use.
+type Admin struct {
+	mu       sync.Mutex
+	stewards map[string]adminSteward
+}
+
+// adminSteward is a steward registered with an Admin.
+type adminSteward struct {
+	control *Control
+	status  *StatusTracker
+}
+
+// NewAdmin returns an Admin without stewards.
+func NewAdmin() *Admin {
+	return &Admin{stewards: make(map[string]adminSteward)}
+}
+
+// Register adds the steward given c and t, with WithControl and WithStatus,
+// under name, replacing any steward already registered under name.  Register
+// panics if c or t is nil, since the Admin needs both to serve the steward.
+func (a *Admin) Register(name string, c *Control, t *StatusTracker) {
+	if c == nil || t == nil {
+		panic("supervise: Admin.Register needs a Control and a StatusTracker")
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.stewards[name] = adminSteward{control: c, status: t}
+}
+
+// Unregister removes the steward registered under name.
+func (a *Admin) Unregister(name string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.stewards, name)
+}
+
+func (a *Admin) lookup(name string) (adminSteward, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	s, ok := a.stewards[name]
+	return s, ok
+}
+
+// ServeHTTP serves the status of the stewards and carries out commands.
+func (a *Admin) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(r.URL.Path, "/")
+
+	if r.Method == http.MethodPost {
+		name, action, _ := cutLast(path, "/")
+		a.command(w, r, name, action)
+		return
+	}
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if path == "" {
+		a.list(w)
+		return
+	}
+
+	s, ok := a.lookup(path)
+	if !ok {
+		http.Error(w, "no steward named "+path, http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, newStatusJSON(path, s.status.Status()))
+}
+
+// list writes the status of every steward, ordered by name.
+func (a *Admin) list(w http.ResponseWriter) {
+	a.mu.Lock()
+	statuses := make([]statusJSON, 0, len(a.stewards))
+	for name, s := range a.stewards {
+		statuses = append(statuses, newStatusJSON(name, s.status.Status()))
+	}
+	a.mu.Unlock()
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Name < statuses[j].Name
+	})
+
+	writeJSON(w, http.StatusOK, statuses)
+}
+
+// command sends the named action to the steward registered under name.
+func (a *Admin) command(w http.ResponseWriter, r *http.Request, name, action string) {
+	s, ok := a.lookup(name)
+	if !ok {
+		http.Error(w, "no steward named "+name, http.StatusNotFound)
+		return
+	}
+
+	var send func(context.Context) error
+	switch action {
+	case "pause":
+		send = s.control.Pause
+	case "resume":
+		send = s.control.Resume
+	case "restart":
+		send = s.control.Restart
+	case "stop":
+		send = s.control.Stop
+	default:
+		http.Error(w, "unknown command "+action, http.StatusNotFound)
+		return
+	}
+
+	if err := send(r.Context()); err != nil {
+		code := http.StatusServiceUnavailable
+		if errors.Is(err, ErrPaused) || errors.Is(err, ErrStewardStopped) {
+			code = http.StatusConflict
+		}
+		http.Error(w, err.Error(), code)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, newStatusJSON(name, s.status.Status()))
+}
+
+// cutLast slices s around the last instance of sep.
+func cutLast(s, sep string) (before, after string, found bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return "", s, false
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+// statusJSON is the JSON form of a Status.
+type statusJSON struct {
+	Name           string
+	State          string
+	Attempt        int
+	Generation     int
+	Endpoint       int
+	ConnectedSince *time.Time `json:",omitempty"`
+	LastError      string     `json:",omitempty"`
+	Restarts       []restartJSON
+}
+
+// restartJSON is the JSON form of a Restart.
+type restartJSON struct {
+	At         time.Time
+	Generation int
+	Cause      string `json:",omitempty"`
+	Uptime     string
+	Downtime   string `json:",omitempty"`
+}
+
+func newStatusJSON(name string, s Status) statusJSON {
+	j := statusJSON{
+		Name:       name,
+		State:      s.State.String(),
+		Attempt:    s.Attempt,
+		Generation: s.Generation,
+		Endpoint:   s.Endpoint,
+		Restarts:   make([]restartJSON, 0, len(s.Restarts)),
+	}
+
+	if !s.ConnectedSince.IsZero() {
+		j.ConnectedSince = &s.ConnectedSince
+	}
+	if s.LastError != nil {
+		j.LastError = s.LastError.Error()
+	}
+
+	for _, r := range s.Restarts {
+		rj := restartJSON{
+			At:         r.At,
+			Generation: r.Generation,
+			Uptime:     r.Uptime.String(),
+		}
+		if r.Cause != nil {
+			rj.Cause = r.Cause.Error()
+		}
+		if r.Downtime > 0 {
+			rj.Downtime = r.Downtime.String()
+		}
+		j.Restarts = append(j.Restarts, rj)
+	}
+
+	return j
+}
@@ -0,0 +1,166 @@
+package supervise_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mstreet3/go-blogs/supervise"
+	"github.com/mstreet3/go-blogs/supervise/faults"
+)
+
+// adminStatus is the part of the Admin's JSON status that tests look at.
+type adminStatus struct {
+	Name       string
+	State      string
+	Generation int
+}
+
+// request sends method to the Admin served by srv at path and decodes any
+// status it sends back into v.
+func request(t *testing.T, srv *httptest.Server, method, path string, v any) int {
+	t.Helper()
+
+	req, err := http.NewRequest(method, srv.URL+path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK && v != nil {
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func TestAdminCommandsStewards(t *testing.T) {
+	network := faults.NewNetwork(1)
+	control := supervise.NewControl()
+	tracker := supervise.NewStatusTracker(5)
+	admin := supervise.NewAdmin()
+	admin.Register("feed", control, tracker)
+	srv := httptest.NewServer(http.StripPrefix("/admin", admin))
+	defer srv.Close()
+
+	done, _ := supervise.ConnectionSteward(make(chan struct{}), network,
+		time.Millisecond, supervise.WithControl(control),
+		supervise.WithStatus(tracker),
+		supervise.WithBackoff(supervise.ConstantBackoff(time.Hour)))
+	eventually(t, "a running ward", func() bool {
+		return tracker.Status().State == supervise.StateRunning
+	})
+
+	var s adminStatus
+	if code := request(t, srv, http.MethodGet, "/admin/feed", &s); code != http.StatusOK {
+		t.Fatalf("GET /admin/feed: %d", code)
+	}
+	if s != (adminStatus{Name: "feed", State: "running", Generation: 1}) {
+		t.Errorf("GET /admin/feed sent %+v", s)
+	}
+
+	// A restart skips the hour-long backoff.
+	if code := request(t, srv, http.MethodPost, "/admin/feed/restart", nil); code != http.StatusOK {
+		t.Fatalf("restart: %d", code)
+	}
+	eventually(t, "a second ward", func() bool {
+		s := tracker.Status()
+		return s.State == supervise.StateRunning && s.Generation == 2
+	})
+
+	tests := []struct {
+		action string
+		code   int
+		state  string
+	}{
+		{"pause", http.StatusOK, "paused"},
+		{"restart", http.StatusConflict, ""},
+		{"bogus", http.StatusNotFound, ""},
+		{"resume", http.StatusOK, ""},
+		{"stop", http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		var s adminStatus
+		code := request(t, srv, http.MethodPost, "/admin/feed/"+tt.action, &s)
+		if code != tt.code {
+			t.Errorf("%s: got %d, want %d", tt.action, code, tt.code)
+		}
+		if tt.state != "" && s.State != tt.state {
+			t.Errorf("%s: steward is %q, want %q", tt.action, s.State, tt.state)
+		}
+	}
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("steward did not stop")
+	}
+	if got := network.Attempts(); got != 3 {
+		t.Errorf("connected %d times, want 3", got)
+	}
+	if code := request(t, srv, http.MethodPost, "/admin/feed/pause", nil); code != http.StatusConflict {
+		t.Errorf("pause after stopping: got %d, want %d", code, http.StatusConflict)
+	}
+}
+
+func TestAdminListsStewardsByName(t *testing.T) {
+	admin := supervise.NewAdmin()
+	for _, name := range []string{"b", "c", "a"} {
+		admin.Register(name, supervise.NewControl(), supervise.NewStatusTracker(1))
+	}
+	admin.Unregister("c")
+	srv := httptest.NewServer(admin)
+	defer srv.Close()
+
+	var list []adminStatus
+	if code := request(t, srv, http.MethodGet, "/", &list); code != http.StatusOK {
+		t.Fatalf("GET /: %d", code)
+	}
+	want := []adminStatus{{Name: "a", State: "idle"}, {Name: "b", State: "idle"}}
+	if len(list) != len(want) || list[0] != want[0] || list[1] != want[1] {
+		t.Errorf("GET / sent %+v, want %+v", list, want)
+	}
+
+	tests := []struct {
+		method, path string
+		code         int
+	}{
+		{http.MethodGet, "/c", http.StatusNotFound},
+		{http.MethodPost, "/c/pause", http.StatusNotFound},
+		{http.MethodDelete, "/a", http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		if code := request(t, srv, tt.method, tt.path, nil); code != tt.code {
+			t.Errorf("%s %s: got %d, want %d", tt.method, tt.path, code, tt.code)
+		}
+	}
+}
+
+func TestAdminRegisterNeedsAControlAndATracker(t *testing.T) {
+	tests := []struct {
+		name    string
+		control *supervise.Control
+		tracker *supervise.StatusTracker
+	}{
+		{"no control", nil, supervise.NewStatusTracker(1)},
+		{"no tracker", supervise.NewControl(), nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("Register did not panic")
+				}
+			}()
+			supervise.NewAdmin().Register("feed", tt.control, tt.tracker)
+		})
+	}
+}
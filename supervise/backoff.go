@@ -0,0 +1,131 @@
+package supervise
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// maxDelay bounds every computed delay well clear of overflowing a
+// time.Duration.
+const maxDelay = 1 << 62
+
+// Backoff decides how long a steward waits before its next attempt to
+// connect, either after a failed connection or after restarting an unhealthy
+// ward.
+type Backoff interface {
+	// Delay returns the delay before the given attempt, counting from 1
+	// since the last reset, and the delay returned for the previous attempt
+	// or zero.
+	Delay(attempt int, prev time.Duration) time.Duration
+}
+
+// BackoffFunc adapts an ordinary function to a Backoff.
+type BackoffFunc func(attempt int, prev time.Duration) time.Duration
+
+// Delay calls f(attempt, prev).
+func (f BackoffFunc) Delay(attempt int, prev time.Duration) time.Duration {
+	return f(attempt, prev)
+}
+
+// ConstantBackoff waits the same duration before every attempt.
+type ConstantBackoff time.Duration
+
+// Delay returns b.
+func (b ConstantBackoff) Delay(int, time.Duration) time.Duration {
+	return time.Duration(b)
+}
+
+// ExponentialBackoff multiplies its delay by Factor on every attempt, starting
+// from Base and capped at Max.
+type ExponentialBackoff struct {
+	// Base is the delay before the first attempt.
+	Base time.Duration
+
+	// Max caps the delay.  A zero Max leaves the delay uncapped.
+	Max time.Duration
+
+	// Factor is the growth of the delay per attempt and defaults to 2.
+	Factor float64
+
+	// Jitter is the fraction of each delay, between 0 and 1, that is
+	// randomised so that many stewards do not retry in lockstep.
+	Jitter float64
+}
+
+// Delay returns Base * Factor^(attempt-1) capped at Max with Jitter applied.
+func (b ExponentialBackoff) Delay(attempt int, _ time.Duration) time.Duration {
+	factor := b.Factor
+	if factor < 1 {
+		factor = 2
+	}
+
+	if attempt < 1 {
+		attempt = 1
+	}
+
+	d := float64(b.Base) * math.Pow(factor, float64(attempt-1))
+	if b.Max > 0 && d > float64(b.Max) {
+		d = float64(b.Max)
+	}
+
+	if d > maxDelay {
+		d = maxDelay
+	}
+
+	if b.Jitter > 0 {
+		jitter := math.Min(b.Jitter, 1)
+		d -= d * jitter * rand.Float64()
+	}
+
+	return time.Duration(d)
+}
+
+// DecorrelatedJitterBackoff picks each delay at random between Base and three
+// times the previous delay, capped at Max.
+type DecorrelatedJitterBackoff struct {
+	// Base is the smallest delay.
+	Base time.Duration
+
+	// Max caps the delay.  A zero Max leaves the delay uncapped.
+	Max time.Duration
+}
+
+// Delay returns a random duration in [Base, 3*prev] capped at Max.
+func (b DecorrelatedJitterBackoff) Delay(_ int, prev time.Duration) time.Duration {
+	upper := 3 * prev
+	if prev > maxDelay/3 {
+		upper = maxDelay
+	}
+
+	d := b.Base
+	if upper > b.Base {
+		d += time.Duration(rand.Int63n(int64(upper - b.Base)))
+	}
+
+	if b.Max > 0 && d > b.Max {
+		d = b.Max
+	}
+
+	return d
+}
+
+// backoffState counts a steward's attempts since its backoff was last reset.
+type backoffState struct {
+	backoff Backoff
+	attempt int
+	prev    time.Duration
+}
+
+// next returns the delay before the next attempt.
+func (s *backoffState) next() time.Duration {
+	s.attempt++
+	s.prev = s.backoff.Delay(s.attempt, s.prev)
+	return s.prev
+}
+
+// reset starts the backoff again from its first attempt.
+func (s *backoffState) reset() {
+	s.attempt = 0
+	s.prev = 0
+}
@@ -0,0 +1,126 @@
+package supervise_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mstreet3/go-blogs/supervise"
+	"github.com/mstreet3/go-blogs/supervise/faults"
+)
+
+func TestExponentialBackoff(t *testing.T) {
+	tests := []struct {
+		name    string
+		backoff supervise.ExponentialBackoff
+		attempt int
+		want    time.Duration
+	}{
+		{"first", supervise.ExponentialBackoff{Base: 10 * time.Millisecond}, 1, 10 * time.Millisecond},
+		{"doubles", supervise.ExponentialBackoff{Base: 10 * time.Millisecond}, 4, 80 * time.Millisecond},
+		{"factor", supervise.ExponentialBackoff{Base: 10 * time.Millisecond, Factor: 3}, 3, 90 * time.Millisecond},
+		{"capped", supervise.ExponentialBackoff{Base: 10 * time.Millisecond, Max: 50 * time.Millisecond}, 4, 50 * time.Millisecond},
+		{"zero attempt", supervise.ExponentialBackoff{Base: 10 * time.Millisecond}, 0, 10 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.backoff.Delay(tt.attempt, 0); got != tt.want {
+				t.Errorf("Delay(%d) = %v, want %v", tt.attempt, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExponentialBackoffDoesNotOverflow(t *testing.T) {
+	b := supervise.ExponentialBackoff{Base: time.Second}
+	if got := b.Delay(1000, 0); got <= 0 {
+		t.Errorf("Delay(1000) = %v, want a positive delay", got)
+	}
+}
+
+func TestExponentialBackoffJitter(t *testing.T) {
+	b := supervise.ExponentialBackoff{Base: 100 * time.Millisecond, Jitter: 0.5}
+	for i := 0; i < 100; i++ {
+		if got := b.Delay(1, 0); got < 50*time.Millisecond || got > 100*time.Millisecond {
+			t.Fatalf("Delay(1) = %v, want between 50ms and 100ms", got)
+		}
+	}
+}
+
+func TestDecorrelatedJitterBackoffStaysWithinBounds(t *testing.T) {
+	b := supervise.DecorrelatedJitterBackoff{Base: 10 * time.Millisecond, Max: time.Second}
+
+	var prev time.Duration
+	for attempt := 1; attempt <= 100; attempt++ {
+		d := b.Delay(attempt, prev)
+		if d < b.Base || d > b.Max {
+			t.Fatalf("Delay(%d, %v) = %v, want between %v and %v",
+				attempt, prev, d, b.Base, b.Max)
+		}
+		if upper := 3 * prev; prev > 0 && upper > b.Base && d > upper {
+			t.Fatalf("Delay(%d, %v) = %v, want at most %v", attempt, prev, d, upper)
+		}
+		prev = d
+	}
+}
+
+func TestConnectionStewardWaitsForTheBackoffBetweenConnections(t *testing.T) {
+	clock := supervise.NewFakeClock(time.Unix(0, 0))
+	network := faults.NewNetwork(1, faults.ConnectFailures(nil, 1))
+	stop := make(chan struct{})
+
+	type call struct {
+		attempt int
+		prev    time.Duration
+	}
+	var (
+		mu    sync.Mutex
+		calls []call
+	)
+	backoff := supervise.BackoffFunc(func(attempt int, prev time.Duration) time.Duration {
+		mu.Lock()
+		defer mu.Unlock()
+		calls = append(calls, call{attempt, prev})
+		return time.Duration(attempt) * 10 * time.Millisecond
+	})
+
+	done, errs := supervise.ConnectionSteward(stop, network, time.Millisecond,
+		supervise.WithBackoff(backoff), supervise.WithClock(clock))
+	go func() {
+		for range errs {
+		}
+	}()
+
+	for attempt := 1; attempt <= 3; attempt++ {
+		clock.BlockUntil(1)
+		if got := network.Attempts(); got != attempt {
+			t.Fatalf("connected %d times, want %d", got, attempt)
+		}
+
+		delay := time.Duration(attempt) * 10 * time.Millisecond
+		clock.Advance(delay - time.Nanosecond)
+		time.Sleep(5 * time.Millisecond)
+		if got := network.Attempts(); got != attempt {
+			t.Fatalf("connected %d times before the backoff passed, want %d",
+				got, attempt)
+		}
+
+		clock.Advance(time.Nanosecond)
+		eventually(t, "the next connection", func() bool {
+			return network.Attempts() == attempt+1
+		})
+	}
+
+	close(stop)
+	<-done
+
+	mu.Lock()
+	defer mu.Unlock()
+	for i, c := range calls[:3] {
+		want := call{i + 1, time.Duration(i) * 10 * time.Millisecond}
+		if c != want {
+			t.Errorf("call %d got %+v, want %+v", i, c, want)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package supervise
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock tells the time and schedules the ticks, timeouts and delays of the
+// workers in this package.  The default is the system clock; tests can use a
+// FakeClock instead to play out heal-and-restart timelines without sleeping.
+type Clock interface {
+	Now() time.Time
+	NewTicker(d time.Duration) Ticker
+	NewTimer(d time.Duration) Timer
+	Sleep(d time.Duration)
+}
+
+// Ticker delivers ticks on C at intervals, like a *time.Ticker.
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// Timer delivers a single tick on C, like a *time.Timer.
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+	Reset(d time.Duration) bool
+}
+
+// SystemClock returns the Clock backed by the time package.
+func SystemClock() Clock {
+	return systemClock{}
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time        { return time.Now() }
+func (systemClock) Sleep(d time.Duration) { time.Sleep(d) }
+
+func (systemClock) NewTicker(d time.Duration) Ticker {
+	return systemTicker{time.NewTicker(d)}
+}
+
+func (systemClock) NewTimer(d time.Duration) Timer {
+	return systemTimer{time.NewTimer(d)}
+}
+
+type systemTicker struct{ *time.Ticker }
+
+func (t systemTicker) C() <-chan time.Time { return t.Ticker.C }
+
+type systemTimer struct{ *time.Timer }
+
+func (t systemTimer) C() <-chan time.Time { return t.Timer.C }
+
+// FakeClock is a Clock whose time only moves when Advance is called.  Timers
+// and tickers fire, in order, as Advance moves the time past them.  Like their
+// counterparts in the time package they drop ticks that are not received in
+// time.  A FakeClock is safe for concurrent use.
+type FakeClock struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	now     time.Time
+	waiters []*fakeWaiter
+}
+
+// NewFakeClock returns a FakeClock set to start.
+func NewFakeClock(start time.Time) *FakeClock {
+	c := &FakeClock{now: start}
+	c.cond = sync.NewCond(&c.mu)
+	return c
+}
+
+// fakeWaiter is a pending timer, or a ticker when period is positive.
+type fakeWaiter struct {
+	clock  *FakeClock
+	at     time.Time
+	period time.Duration
+	c      chan time.Time
+}
+
+// Now returns the time of the clock.
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// NewTicker returns a Ticker that fires every d once the clock is advanced.
+// It panics if d is not positive, like time.NewTicker.
+func (c *FakeClock) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("supervise: non-positive interval for FakeClock.NewTicker")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	w := &fakeWaiter{clock: c, period: d, c: make(chan time.Time, 1)}
+	c.schedule(w, d)
+	return fakeTicker{w}
+}
+
+// NewTimer returns a Timer that fires once the clock is advanced by d.
+func (c *FakeClock) NewTimer(d time.Duration) Timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	w := &fakeWaiter{clock: c, c: make(chan time.Time, 1)}
+	c.schedule(w, d)
+	return w
+}
+
+// Sleep blocks until the clock is advanced by d.
+func (c *FakeClock) Sleep(d time.Duration) {
+	<-c.NewTimer(d).C()
+}
+
+// Advance moves the clock forward by d, firing every timer and ticker that
+// falls due on the way in order of their deadlines.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	end := c.now.Add(d)
+	for {
+		next := -1
+		for i, w := range c.waiters {
+			if !w.at.After(end) && (next < 0 || w.at.Before(c.waiters[next].at)) {
+				next = i
+			}
+		}
+		if next < 0 {
+			break
+		}
+
+		w := c.waiters[next]
+		c.now = w.at
+		w.fire()
+		if w.period > 0 {
+			w.at = w.at.Add(w.period)
+		} else {
+			c.remove(w)
+		}
+	}
+
+	c.now = end
+}
+
+// BlockUntil blocks until at least n timers, tickers and sleepers are waiting
+// on the clock, so that a test can advance it once the workers it drives are
+// ready.
+func (c *FakeClock) BlockUntil(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for len(c.waiters) < n {
+		c.cond.Wait()
+	}
+}
+
+// schedule makes w fire after d, firing it at once if d is not positive.  The
+// caller must hold c.mu.
+func (c *FakeClock) schedule(w *fakeWaiter, d time.Duration) {
+	if d <= 0 && w.period == 0 {
+		w.fire()
+		return
+	}
+
+	w.at = c.now.Add(d)
+	c.waiters = append(c.waiters, w)
+	c.cond.Broadcast()
+}
+
+// remove reports whether w was waiting and stops it.  The caller must hold
+// c.mu.
+func (c *FakeClock) remove(w *fakeWaiter) bool {
+	for i, other := range c.waiters {
+		if other == w {
+			c.waiters = append(c.waiters[:i], c.waiters[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// fire sends the time on w without blocking.  The caller must hold the
+// clock's mutex.
+func (w *fakeWaiter) fire() {
+	select {
+	case w.c <- w.clock.now:
+	default:
+	}
+}
+
+func (w *fakeWaiter) C() <-chan time.Time {
+	return w.c
+}
+
+func (w *fakeWaiter) Stop() bool {
+	w.clock.mu.Lock()
+	defer w.clock.mu.Unlock()
+	return w.clock.remove(w)
+}
+
+func (w *fakeWaiter) Reset(d time.Duration) bool {
+	w.clock.mu.Lock()
+	defer w.clock.mu.Unlock()
+
+	active := w.clock.remove(w)
+	w.clock.schedule(w, d)
+	return active
+}
+
+// fakeTicker is a fakeWaiter with the methods of a Ticker.
+type fakeTicker struct{ w *fakeWaiter }
+
+func (t fakeTicker) C() <-chan time.Time { return t.w.c }
+func (t fakeTicker) Stop()               { t.w.Stop() }
@@ -0,0 +1,153 @@
+package supervise_test
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/mstreet3/go-blogs/supervise"
+	"github.com/mstreet3/go-blogs/supervise/faults"
+)
+
+var epoch = time.Unix(0, 0)
+
+// fired reports whether c has a tick ready.
+func fired(c <-chan time.Time) bool {
+	select {
+	case <-c:
+		return true
+	default:
+		return false
+	}
+}
+
+func TestFakeClockFiresTimersOnceTheyAreDue(t *testing.T) {
+	clock := supervise.NewFakeClock(epoch)
+	short := clock.NewTimer(time.Second)
+	long := clock.NewTimer(2 * time.Second)
+
+	clock.Advance(999 * time.Millisecond)
+	if fired(short.C()) || fired(long.C()) {
+		t.Fatal("a timer fired early")
+	}
+
+	clock.Advance(time.Millisecond)
+	select {
+	case at := <-short.C():
+		if want := epoch.Add(time.Second); !at.Equal(want) {
+			t.Errorf("timer fired at %v, want %v", at, want)
+		}
+	default:
+		t.Fatal("timer did not fire")
+	}
+	if fired(long.C()) {
+		t.Fatal("the later timer fired early")
+	}
+
+	if !long.Stop() {
+		t.Error("Stop of a pending timer returned false")
+	}
+	clock.Advance(time.Hour)
+	if fired(long.C()) {
+		t.Error("a stopped timer fired")
+	}
+	if got, want := clock.Now(), epoch.Add(time.Hour+time.Second); !got.Equal(want) {
+		t.Errorf("Now() = %v, want %v", got, want)
+	}
+}
+
+func TestFakeClockResetsTimers(t *testing.T) {
+	clock := supervise.NewFakeClock(epoch)
+	timer := clock.NewTimer(time.Second)
+
+	if !timer.Reset(3 * time.Second) {
+		t.Error("Reset of a pending timer returned false")
+	}
+	clock.Advance(2 * time.Second)
+	if fired(timer.C()) {
+		t.Fatal("timer fired at its old deadline")
+	}
+	clock.Advance(time.Second)
+	if !fired(timer.C()) {
+		t.Fatal("timer did not fire at its new deadline")
+	}
+	if timer.Reset(time.Second) {
+		t.Error("Reset of a fired timer returned true")
+	}
+}
+
+func TestFakeClockTicksEveryPeriod(t *testing.T) {
+	clock := supervise.NewFakeClock(epoch)
+	ticker := clock.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for i := 1; i <= 3; i++ {
+		clock.Advance(time.Second)
+		select {
+		case at := <-ticker.C():
+			if want := epoch.Add(time.Duration(i) * time.Second); !at.Equal(want) {
+				t.Errorf("tick %d at %v, want %v", i, at, want)
+			}
+		default:
+			t.Fatalf("no tick %d", i)
+		}
+	}
+
+	// Like a time.Ticker, ticks are dropped for a slow receiver.
+	clock.Advance(5 * time.Second)
+	if !fired(ticker.C()) || fired(ticker.C()) {
+		t.Error("want exactly one tick after a long advance")
+	}
+}
+
+func TestFakeClockBlockUntilWaitsForSleepers(t *testing.T) {
+	clock := supervise.NewFakeClock(epoch)
+	woke := make(chan struct{})
+
+	go func() {
+		clock.Sleep(time.Minute)
+		close(woke)
+	}()
+
+	clock.BlockUntil(1)
+	clock.Advance(time.Minute)
+
+	select {
+	case <-woke:
+	case <-time.After(time.Second):
+		t.Fatal("sleeper did not wake")
+	}
+}
+
+func TestConnectionStewardFollowsItsClock(t *testing.T) {
+	clock := supervise.NewFakeClock(epoch)
+	network := faults.NewNetwork(1, faults.ConnectScript(
+		faults.ErrConnectRefused, faults.ErrConnectRefused, nil))
+	stop := make(chan struct{})
+	events := make(chan supervise.Event, 100)
+
+	done, _ := supervise.ConnectionSteward(stop, network, time.Millisecond,
+		supervise.WithClock(clock), supervise.WithEvents(events),
+		supervise.WithBackoff(supervise.ConstantBackoff(time.Minute)))
+
+	for i := 0; i < 2; i++ {
+		clock.BlockUntil(1)
+		clock.Advance(time.Minute)
+	}
+	eventually(t, "a connection", func() bool { return network.Attempts() == 3 })
+	close(stop)
+	<-done
+	close(events)
+
+	var connecting []time.Duration
+	for e := range events {
+		if e.Kind == supervise.Connecting {
+			connecting = append(connecting, e.At.Sub(epoch))
+		}
+	}
+
+	want := []time.Duration{0, time.Minute, 2 * time.Minute}
+	if !slices.Equal(connecting, want) {
+		t.Errorf("connected at %v, want %v", connecting, want)
+	}
+}
@@ -0,0 +1,108 @@
+package supervise
+
+import (
+	"context"
+	"errors"
+)
+
+// errStopped is the cancellation cause of workers that were stopped by
+// closing a stop channel or by their steward.  It is never reported on an
+// error channel.
+var errStopped = errors.New("supervise: stopped")
+
+// ContextConnector is implemented by a Connector whose connections honour the
+// cancellation and deadline of a context.
+type ContextConnector interface {
+	ConnectContext(ctx context.Context) (Conn, error)
+}
+
+// ContextReader is implemented by a Reader whose reads honour the
+// cancellation and deadline of a context.
+type ContextReader interface {
+	ReadContext(ctx context.Context) (*Message, error)
+}
+
+// connect connects to network with ctx if network is a ContextConnector.
+func connect(ctx context.Context, network Connector) (Conn, error) {
+	if c, ok := network.(ContextConnector); ok {
+		return c.ConnectContext(ctx)
+	}
+	return network.Connect()
+}
+
+// read reads from conn with ctx if conn is a ContextReader.
+func read(ctx context.Context, conn Reader) (*Message, error) {
+	if r, ok := conn.(ContextReader); ok {
+		return r.ReadContext(ctx)
+	}
+	return conn.Read()
+}
+
+// stopContext returns a context that is cancelled with errStopped once stop
+// is closed.  The returned release function must be called once the context
+// is no longer needed.
+func stopContext(stop <-chan struct{}) (context.Context, func()) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+
+	go func() {
+		select {
+		case <-stop:
+		case <-ctx.Done():
+		}
+		cancel(errStopped)
+	}()
+
+	return ctx, func() { cancel(errStopped) }
+}
+
+// closedSignal is an already closed done channel.
+var closedSignal = func() <-chan struct{} {
+	c := make(chan struct{})
+	close(c)
+	return c
+}()
+
+// isClosed reports whether signal is closed.  A nil signal is never closed.
+func isClosed(signal <-chan struct{}) bool {
+	select {
+	case <-signal:
+		return true
+	default:
+		return false
+	}
+}
+
+// releaseWhenDone calls release once done is closed.
+func releaseWhenDone(done <-chan struct{}, release func()) {
+	go func() {
+		<-done
+		release()
+	}()
+}
+
+// sendCause reports the cancellation cause of ctx on errs unless the worker
+// was simply stopped.  See sendLast.
+func sendCause(ctx context.Context, errs chan error, dropped func(error)) {
+	cause := context.Cause(ctx)
+	if cause == nil || errors.Is(cause, errStopped) {
+		return
+	}
+
+	sendLast(errs, cause, dropped)
+}
+
+// sendLast sends the final error of a worker on errs without blocking.  errs
+// must only be sent on by the calling worker; if its buffer is full the
+// pending error is passed to dropped and replaced by err.
+func sendLast(errs chan error, err error, dropped func(error)) {
+	select {
+	case errs <- err:
+	default:
+		select {
+		case pending := <-errs:
+			dropped(pending)
+		default:
+		}
+		errs <- err
+	}
+}
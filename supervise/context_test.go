@@ -0,0 +1,73 @@
+package supervise_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mstreet3/go-blogs/supervise"
+	"github.com/mstreet3/go-blogs/supervise/faults"
+)
+
+// contextReader records the context of every read.
+type contextReader struct {
+	contexts chan context.Context
+}
+
+func (r *contextReader) Read() (*supervise.Message, error) {
+	return r.ReadContext(context.Background())
+}
+
+func (r *contextReader) ReadContext(ctx context.Context) (*supervise.Message, error) {
+	select {
+	case r.contexts <- ctx:
+	default:
+	}
+	return &supervise.Message{Content: "a"}, nil
+}
+
+func TestReaderWardContextReportsTheCauseOfItsContext(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	conn := &contextReader{contexts: make(chan context.Context, 1)}
+
+	done, errs := supervise.ReaderWardContext(ctx, conn, time.Millisecond)
+
+	var last error
+	for err := range errs {
+		last = err
+	}
+	<-done
+
+	if !errors.Is(last, context.DeadlineExceeded) {
+		t.Errorf("got error %v, want %v", last, context.DeadlineExceeded)
+	}
+	if _, ok := (<-conn.contexts).Deadline(); !ok {
+		t.Error("read without the ward's deadline")
+	}
+}
+
+func TestConnectionStewardContextReportsTheCauseOfItsContext(t *testing.T) {
+	errShutdown := errors.New("shutdown")
+	network := faults.NewNetwork(1)
+	ctx, cancel := context.WithCancelCause(context.Background())
+
+	done, errs := supervise.ConnectionStewardContext(ctx, network,
+		time.Millisecond)
+	eventually(t, "a connection", func() bool { return network.Attempts() == 1 })
+	cancel(errShutdown)
+
+	var last error
+	for err := range errs {
+		last = err
+	}
+	<-done
+
+	if last != errShutdown {
+		t.Errorf("got error %v, want %v", last, errShutdown)
+	}
+	if got := network.Closes(); got != 1 {
+		t.Errorf("closed %d connections, want 1", got)
+	}
+}
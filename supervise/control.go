@@ -0,0 +1,174 @@
+package supervise
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+var (
+	// ErrRestartRequested is the cause of a restart forced by a Control.
+	ErrRestartRequested = errors.New("supervise: restart requested")
+
+	// ErrPaused is returned by Control.Restart while the steward is
+	// paused.
+	ErrPaused = errors.New("supervise: steward paused")
+
+	// ErrStewardStopped is returned by a Control whose steward is done.
+	ErrStewardStopped = errors.New("supervise: steward stopped")
+)
+
+// commandKind identifies a command sent by a Control.
+type commandKind int
+
+const (
+	pauseCommand commandKind = iota
+	resumeCommand
+	restartCommand
+	stopCommand
+)
+
+// command is carried out by the steward's goroutine, which replies once it
+// has been.
+type command struct {
+	kind  commandKind
+	reply chan error
+}
+
+// Control sends commands to a running steward.  Commands are received by the
+// steward's own goroutine between the steps of its lifecycle, so a command
+// sent while the steward connects or closes a connection waits for it to
+// finish.  A Control is safe for concurrent use but must be given to only one
+// steward.
+type Control struct {
+	commands chan command
+	done     chan struct{}
+	once     sync.Once
+}
+
+// NewControl returns a Control to give to a steward with WithControl.
+func NewControl() *Control {
+	return &Control{
+		commands: make(chan command),
+		done:     make(chan struct{}),
+	}
+}
+
+// Pause stops the steward's ward and closes its connection until Resume is
+// called.  Pausing a paused steward does nothing.
+func (c *Control) Pause(ctx context.Context) error {
+	return c.send(ctx, pauseCommand)
+}
+
+// Resume makes a paused steward connect again.  Resuming a steward that is
+// not paused does nothing.
+func (c *Control) Resume(ctx context.Context) error {
+	return c.send(ctx, resumeCommand)
+}
+
+// Restart replaces the steward's ward and connection without waiting for its
+// backoff, and makes a steward waiting to connect again try at once.  The
+// restart counts in the steward's history but not towards its restart
+// intensity.
+func (c *Control) Restart(ctx context.Context) error {
+	return c.send(ctx, restartCommand)
+}
+
+// Stop stops the steward as if its context were done, draining it if
+// WithDrain was given.
+func (c *Control) Stop(ctx context.Context) error {
+	return c.send(ctx, stopCommand)
+}
+
+// send hands a command to the steward and waits for its reply until ctx is
+// done.
+func (c *Control) send(ctx context.Context, kind commandKind) error {
+	cmd := command{kind: kind, reply: make(chan error, 1)}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-c.done:
+		return ErrStewardStopped
+	case c.commands <- cmd:
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-cmd.reply:
+		return err
+	}
+}
+
+// listen returns the channel of commands, or nil for a nil Control.
+func (c *Control) listen() <-chan command {
+	if c == nil {
+		return nil
+	}
+	return c.commands
+}
+
+// close makes every later command fail with ErrStewardStopped.
+func (c *Control) close() {
+	if c == nil {
+		return
+	}
+	c.once.Do(func() {
+		close(c.done)
+	})
+}
+
+// obey carries out cmd while w is reading and returns why w must stop, or nil
+// to keep reading.
+func (s *steward) obey(w *wardRun, cmd command) *wardExit {
+	switch cmd.kind {
+	case pauseCommand:
+		w.logger.Info("pausing ward")
+		return &wardExit{paused: &cmd}
+	case restartCommand:
+		w.logger.Info("restarting ward on request")
+		s.emit(WardUnhealthy, ErrRestartRequested)
+		cmd.reply <- nil
+		return &wardExit{cause: ErrRestartRequested}
+	default:
+		s.obeyAlways(cmd)
+		return nil
+	}
+}
+
+// obeyAlways carries out the commands that mean the same whatever the steward
+// is doing: stop stops it and the others do nothing.
+func (s *steward) obeyAlways(cmd command) {
+	if cmd.kind == stopCommand {
+		s.cancel(errStopped)
+	}
+	cmd.reply <- nil
+}
+
+// pause waits for the steward to be resumed or stopped after replying to the
+// command that paused it.
+func (s *steward) pause(cmd command) {
+	s.logger.Info("paused")
+	s.emit(Paused, nil)
+	cmd.reply <- nil
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case cmd := <-s.commands:
+			switch cmd.kind {
+			case resumeCommand:
+				s.logger.Info("resumed")
+				s.emit(Resumed, nil)
+				cmd.reply <- nil
+				return
+			case restartCommand:
+				cmd.reply <- ErrPaused
+			default:
+				s.obeyAlways(cmd)
+			}
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package supervise_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mstreet3/go-blogs/supervise"
+	"github.com/mstreet3/go-blogs/supervise/faults"
+)
+
+func TestControlPausesAndResumesTheSteward(t *testing.T) {
+	ctx := context.Background()
+	network := faults.NewNetwork(1)
+	control := supervise.NewControl()
+	tracker := supervise.NewStatusTracker(1)
+	stop := make(chan struct{})
+
+	done, _ := supervise.ConnectionSteward(stop, network, time.Millisecond,
+		supervise.WithControl(control), supervise.WithStatus(tracker))
+	eventually(t, "a connection", func() bool { return network.Attempts() == 1 })
+
+	if err := control.Pause(ctx); err != nil {
+		t.Fatalf("Pause: %v", err)
+	}
+	if got := network.Closes(); got != 1 {
+		t.Errorf("closed %d connections once paused, want 1", got)
+	}
+	if s := tracker.Status(); s.State != supervise.StatePaused {
+		t.Errorf("state is %v once paused, want %v", s.State, supervise.StatePaused)
+	}
+
+	// A paused steward neither restarts nor connects.
+	if err := control.Restart(ctx); !errors.Is(err, supervise.ErrPaused) {
+		t.Errorf("Restart while paused returned %v, want %v", err, supervise.ErrPaused)
+	}
+	if err := control.Pause(ctx); err != nil {
+		t.Errorf("Pause while paused: %v", err)
+	}
+	if got := network.Attempts(); got != 1 {
+		t.Errorf("connected %d times while paused, want 1", got)
+	}
+
+	if err := control.Resume(ctx); err != nil {
+		t.Fatalf("Resume: %v", err)
+	}
+	eventually(t, "a new connection", func() bool { return network.Attempts() == 2 })
+
+	close(stop)
+	<-done
+
+	// The only connection after pausing is the one made on resuming.
+	if got := network.Attempts(); got != 2 {
+		t.Errorf("connected %d times, want 2", got)
+	}
+}
+
+func TestControlRestartsSkipTheBackoffAndTheRestartIntensity(t *testing.T) {
+	ctx := context.Background()
+	network := faults.NewNetwork(1)
+	control := supervise.NewControl()
+	events := make(chan supervise.Event, 100)
+	stop := make(chan struct{})
+
+	done, errs := supervise.ConnectionSteward(stop, network, time.Millisecond,
+		supervise.WithControl(control), supervise.WithEvents(events),
+		supervise.WithBackoff(supervise.ConstantBackoff(time.Hour)),
+		supervise.WithRestartIntensity(1, time.Hour))
+
+	for i := 1; i <= 3; i++ {
+		eventually(t, "a connection", func() bool { return network.Attempts() == i })
+		if err := control.Restart(ctx); err != nil {
+			t.Fatalf("Restart %d: %v", i, err)
+		}
+	}
+	eventually(t, "a fourth connection", func() bool { return network.Attempts() == 4 })
+
+	close(stop)
+	<-done
+	for err := range errs {
+		t.Errorf("got error %v", err)
+	}
+
+	close(events)
+	restarts := 0
+	for e := range events {
+		if e.Kind == supervise.WardUnhealthy {
+			restarts++
+			if !errors.Is(e.Err, supervise.ErrRestartRequested) {
+				t.Errorf("restarted because of %v, want %v", e.Err, supervise.ErrRestartRequested)
+			}
+		}
+	}
+	if restarts != 3 {
+		t.Errorf("restarted %d times, want 3", restarts)
+	}
+}
+
+func TestControlStopsTheSteward(t *testing.T) {
+	ctx := context.Background()
+	network := faults.NewNetwork(1)
+	control := supervise.NewControl()
+
+	done, errs := supervise.ConnectionSteward(make(chan struct{}), network,
+		time.Millisecond, supervise.WithControl(control))
+	eventually(t, "a connection", func() bool { return network.Attempts() == 1 })
+
+	if err := control.Stop(ctx); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("steward did not stop")
+	}
+	for err := range errs {
+		t.Errorf("got error %v", err)
+	}
+
+	if err := control.Resume(ctx); !errors.Is(err, supervise.ErrStewardStopped) {
+		t.Errorf("Resume after stopping returned %v, want %v", err, supervise.ErrStewardStopped)
+	}
+}
+
+func TestControlGivesUpWhenTheContextIsDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// No steward ever receives the command.
+	if err := supervise.NewControl().Pause(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Pause returned %v, want %v", err, context.Canceled)
+	}
+}
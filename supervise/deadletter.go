@@ -0,0 +1,74 @@
+package supervise
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// DeadLetter is an error that a worker dropped because nobody was ready to
+// receive it.
+type DeadLetter struct {
+	At     time.Time
+	Worker string
+	Err    error
+}
+
+// ErrorCounters counts the errors dropped by workers.  It is safe for
+// concurrent use.
+type ErrorCounters struct {
+	dropped atomic.Uint64
+}
+
+// Dropped returns the number of errors dropped.
+func (c *ErrorCounters) Dropped() uint64 {
+	return c.dropped.Load()
+}
+
+// dropErr accounts for an error that worker dropped.
+func (o *options) dropErr(worker string, err error) {
+	o.log(worker).Warn("dropped error; no error listeners", "err", err)
+
+	if o.errCounters != nil {
+		o.errCounters.dropped.Add(1)
+	}
+
+	if o.deadLetter != nil {
+		o.deadLetter(DeadLetter{At: o.clock.Now(), Worker: worker, Err: err})
+	}
+}
+
+// FanOut copies every error from errs to each of n returned channels until
+// errs is closed, and then closes them.  Like the workers themselves FanOut
+// never waits for a subscriber: an error that a subscriber is not ready for is
+// dropped for that subscriber alone and accounted for by WithErrorCounters
+// and WithDeadLetter.  FanOut lets a monitor and, say, an alerting component
+// observe the same ward.
+func FanOut(errs <-chan error, n int, opts ...Option) []<-chan error {
+	o := newOptions(opts)
+	subs := make([]chan error, n)
+	outs := make([]<-chan error, n)
+	for i := range subs {
+		subs[i] = make(chan error, 1)
+		outs[i] = subs[i]
+	}
+
+	go func() {
+		defer func() {
+			for _, sub := range subs {
+				close(sub)
+			}
+		}()
+
+		for e := range errs {
+			for _, sub := range subs {
+				select {
+				case sub <- e:
+				default:
+					o.dropErr("fanout", e)
+				}
+			}
+		}
+	}()
+
+	return outs
+}
@@ -0,0 +1,92 @@
+package supervise_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mstreet3/go-blogs/supervise"
+	"github.com/mstreet3/go-blogs/supervise/faults"
+)
+
+func TestReaderWardAccountsForDroppedErrors(t *testing.T) {
+	failures := []error{errors.New("1"), errors.New("2"), errors.New("3")}
+	conn := faults.NewReader(1, faults.Script(faults.Fail(failures[0]),
+		faults.Fail(failures[1]), faults.Fail(failures[2])))
+	counters := new(supervise.ErrorCounters)
+	stop := make(chan struct{})
+
+	var (
+		mu      sync.Mutex
+		letters []supervise.DeadLetter
+	)
+	deadLetter := func(l supervise.DeadLetter) {
+		mu.Lock()
+		defer mu.Unlock()
+		letters = append(letters, l)
+	}
+
+	done, errs := supervise.ReaderWard(stop, conn, time.Millisecond,
+		supervise.WithErrorCounters(counters), supervise.WithDeadLetter(deadLetter))
+	eventually(t, "every failed read", func() bool { return conn.Reads() > 3 })
+
+	// The first error waits in the buffer while the others are dropped.
+	if err := <-errs; err != failures[0] {
+		t.Errorf("received %v, want %v", err, failures[0])
+	}
+
+	close(stop)
+	<-done
+
+	if got := counters.Dropped(); got != 2 {
+		t.Errorf("dropped %d errors, want 2", got)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(letters) != 2 {
+		t.Fatalf("got %d dead letters, want 2", len(letters))
+	}
+	for i, l := range letters {
+		if l.Worker != "ward" || l.Err != failures[i+1] || l.At.IsZero() {
+			t.Errorf("dead letter %d is %+v, want the ward's error %v", i, l, failures[i+1])
+		}
+	}
+}
+
+func TestFanOutDropsOnlyForTheSlowSubscriber(t *testing.T) {
+	errA, errB := errors.New("a"), errors.New("b")
+	counters := new(supervise.ErrorCounters)
+	errs := make(chan error)
+
+	outs := supervise.FanOut(errs, 2, supervise.WithErrorCounters(counters))
+
+	errs <- errA
+	if err := <-outs[0]; err != errA {
+		t.Errorf("first subscriber received %v, want %v", err, errA)
+	}
+	errs <- errB
+	if err := <-outs[0]; err != errB {
+		t.Errorf("first subscriber received %v, want %v", err, errB)
+	}
+	eventually(t, "the second error to be dropped", func() bool {
+		return counters.Dropped() == 1
+	})
+	close(errs)
+
+	// The second subscriber kept the first error and missed the second.
+	var got []error
+	for err := range outs[1] {
+		got = append(got, err)
+	}
+	if len(got) != 1 || got[0] != errA {
+		t.Errorf("second subscriber received %v, want [%v]", got, errA)
+	}
+	if _, ok := <-outs[0]; ok {
+		t.Error("first subscriber's channel is still open")
+	}
+	if got := counters.Dropped(); got != 1 {
+		t.Errorf("dropped %d errors, want 1", got)
+	}
+}
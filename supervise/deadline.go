@@ -0,0 +1,97 @@
+package supervise
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrReadTimeout is the cause of a read that outlived the timeout given by
+// WithReadTimeout.
+var ErrReadTimeout = errors.New("supervise: read timed out")
+
+// readResult is the outcome of a call to Read.
+type readResult struct {
+	msg *Message
+	err error
+}
+
+// abandonable adapts a legacy Reader to a ContextReader.
+type abandonable struct {
+	conn    Reader
+	pending chan readResult
+}
+
+// Abandonable adapts conn, whose Read takes no context, to a Reader that is
+// also a ContextReader.  Its ReadContext returns the cause of ctx once ctx is
+// done and abandons the call to Read still in progress; the next read picks
+// up that call's result instead of calling Read again, so that conn is never
+// read from concurrently.  Closing the connection is what finally releases an
+// abandoned Read.  Reads from the adapter itself must not be concurrent.
+func Abandonable(conn Reader) Reader {
+	if _, ok := conn.(ContextReader); ok {
+		return conn
+	}
+	return &abandonable{conn: conn}
+}
+
+// Read reads without a deadline.
+func (a *abandonable) Read() (*Message, error) {
+	return a.ReadContext(context.Background())
+}
+
+// ReadContext waits for the pending Read, starting one if there is none,
+// until ctx is done.
+func (a *abandonable) ReadContext(ctx context.Context) (*Message, error) {
+	if a.pending == nil {
+		pending := make(chan readResult, 1)
+		a.pending = pending
+
+		go func() {
+			msg, err := a.conn.Read()
+			pending <- readResult{msg: msg, err: err}
+		}()
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil, context.Cause(ctx)
+	case r := <-a.pending:
+		a.pending = nil
+		return r.msg, r.err
+	}
+}
+
+// readWithin reads from conn, failing with ErrReadTimeout once timeout passes
+// on clock.  A zero timeout reads without a deadline.
+func readWithin(
+	ctx context.Context, conn Reader, timeout time.Duration, clock Clock,
+) (*Message, error) {
+	if timeout <= 0 {
+		return read(ctx, conn)
+	}
+
+	readCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(errStopped)
+
+	timer := clock.NewTimer(timeout)
+	defer timer.Stop()
+
+	go func() {
+		select {
+		case <-readCtx.Done():
+		case <-timer.C():
+			cancel(fmt.Errorf("%w after %v", ErrReadTimeout, timeout))
+		}
+	}()
+
+	msg, err := read(readCtx, conn)
+	if err != nil && ctx.Err() == nil {
+		if cause := context.Cause(readCtx); errors.Is(cause, ErrReadTimeout) {
+			return nil, cause
+		}
+	}
+
+	return msg, err
+}
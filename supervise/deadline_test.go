@@ -0,0 +1,84 @@
+package supervise_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mstreet3/go-blogs/supervise"
+	"github.com/mstreet3/go-blogs/supervise/faults"
+)
+
+// gatedReader is a legacy Reader whose reads each wait for a message on gate.
+type gatedReader struct {
+	gate  chan string
+	calls atomic.Int32
+}
+
+func (r *gatedReader) Read() (*supervise.Message, error) {
+	r.calls.Add(1)
+	return &supervise.Message{Content: <-r.gate}, nil
+}
+
+func TestAbandonableResumesAnAbandonedRead(t *testing.T) {
+	legacy := &gatedReader{gate: make(chan string)}
+	r := supervise.Abandonable(legacy).(supervise.ContextReader)
+
+	errGaveUp := errors.New("gave up")
+	ctx, cancel := context.WithCancelCause(context.Background())
+	cancel(errGaveUp)
+	if _, err := r.ReadContext(ctx); err != errGaveUp {
+		t.Fatalf("abandoned read returned %v, want %v", err, errGaveUp)
+	}
+
+	// The next read picks up the abandoned call instead of reading again.
+	go func() { legacy.gate <- "a" }()
+	msg, err := r.ReadContext(context.Background())
+	if err != nil || msg.Content != "a" {
+		t.Fatalf("read %v, %v, want a", msg, err)
+	}
+	if got := legacy.calls.Load(); got != 1 {
+		t.Errorf("called Read %d times, want 1", got)
+	}
+}
+
+func TestAbandonableKeepsAContextReader(t *testing.T) {
+	r := &contextReader{contexts: make(chan context.Context, 1)}
+	if got := supervise.Abandonable(r); got != supervise.Reader(r) {
+		t.Errorf("Abandonable wrapped a ContextReader in %T", got)
+	}
+}
+
+func TestConnectionStewardRestartsAWardWhoseReadTimesOut(t *testing.T) {
+	network := faults.NewNetwork(1, faults.Script(faults.OK("1"), faults.Hung()))
+	stop := make(chan struct{})
+	events := make(chan supervise.Event, 100)
+
+	done, _ := supervise.ConnectionSteward(stop, network, time.Millisecond,
+		supervise.WithReadTimeout(20*time.Millisecond), supervise.WithEvents(events))
+
+	var cause error
+	for e := range events {
+		if e.Kind == supervise.WardUnhealthy {
+			cause = e.Err
+			break
+		}
+	}
+	eventually(t, "a second connection", func() bool { return network.Attempts() >= 2 })
+
+	close(stop)
+	go func() {
+		for range events {
+		}
+	}()
+	<-done
+
+	if !errors.Is(cause, supervise.ErrReadTimeout) {
+		t.Errorf("ward was unhealthy because of %v, want %v", cause, supervise.ErrReadTimeout)
+	}
+	if opened, closed := network.Attempts(), network.Closes(); opened != closed {
+		t.Errorf("closed %d of %d connections", closed, opened)
+	}
+}
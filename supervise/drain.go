@@ -0,0 +1,101 @@
+package supervise
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrDrainTimeout is the cause of a DrainError.
+var ErrDrainTimeout = errors.New("supervise: drain timed out")
+
+// DrainError is sent by a steward whose drain deadline, given by WithDrain,
+// passed before its ward and buffered messages were done.
+type DrainError struct {
+	// Deadline is the drain deadline that passed.
+	Deadline time.Duration
+
+	// InFlight reports that the ward was force-stopped while it was still
+	// reading or delivering a message.
+	InFlight bool
+
+	// Buffered is the number of buffered messages never delivered.
+	Buffered int
+}
+
+func (e *DrainError) Error() string {
+	abandoned := fmt.Sprintf("%d buffered messages", e.Buffered)
+	if e.InFlight {
+		abandoned = "an in-flight read and " + abandoned
+	}
+	return fmt.Sprintf("%v after %v: abandoned %s",
+		ErrDrainTimeout, e.Deadline, abandoned)
+}
+
+func (e *DrainError) Unwrap() error {
+	return ErrDrainTimeout
+}
+
+// drainWithin waits for done until the drain deadline, which starts with the
+// first wait, passes and reports whether done was closed in time.
+func (s *steward) drainWithin(done <-chan struct{}) bool {
+	if s.drainStart.IsZero() {
+		s.drainStart = s.o.clock.Now()
+	}
+	if isClosed(done) {
+		return true
+	}
+
+	timer := s.o.clock.NewTimer(s.o.drain - s.o.clock.Now().Sub(s.drainStart))
+	defer timer.Stop()
+
+	select {
+	case <-done:
+		return true
+	case <-timer.C():
+		return false
+	}
+}
+
+// drainWard lets w finish the read in flight, when draining, until the drain
+// deadline passes.
+func (s *steward) drainWard(w *wardRun, reading <-chan struct{}) {
+	if s.o.drain <= 0 {
+		return
+	}
+
+	close(w.draining)
+	if !s.drainWithin(reading) {
+		s.inFlight = true
+	}
+}
+
+// closeOutlet stops the outlet, first flushing it when draining, and returns
+// a *DrainError if the drain deadline passed before everything was
+// delivered.
+func (s *steward) closeOutlet() error {
+	if s.o.drain > 0 {
+		if s.msgs != nil {
+			s.msgs.flush()
+		}
+		s.drainWithin(s.delivering)
+	}
+
+	s.stopOutlet()
+	<-s.delivering
+
+	var buffered int
+	if s.msgs != nil {
+		buffered = s.msgs.abandoned()
+	}
+
+	if s.o.drain <= 0 || (!s.inFlight && buffered == 0) {
+		return nil
+	}
+
+	return &DrainError{
+		Deadline: s.o.drain,
+		InFlight: s.inFlight,
+		Buffered: buffered,
+	}
+}
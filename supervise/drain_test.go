@@ -0,0 +1,240 @@
+package supervise_test
+
+import (
+	"errors"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mstreet3/go-blogs/supervise"
+	"github.com/mstreet3/go-blogs/supervise/faults"
+)
+
+var readModes = []supervise.ReadMode{supervise.Poll, supervise.Stream}
+
+// countedNetwork counts the reads from the connections of a network.
+type countedNetwork struct {
+	supervise.Connector
+	reads atomic.Int64
+}
+
+func (n *countedNetwork) Connect() (supervise.Conn, error) {
+	conn, err := n.Connector.Connect()
+	if err != nil {
+		return nil, err
+	}
+	return countedConn{conn, &n.reads}, nil
+}
+
+type countedConn struct {
+	supervise.Conn
+	reads *atomic.Int64
+}
+
+func (c countedConn) Read() (*supervise.Message, error) {
+	msg, err := c.Conn.Read()
+	c.reads.Add(1)
+	return msg, err
+}
+
+func TestConnectionStewardDrainsTheReadInFlight(t *testing.T) {
+	for _, mode := range readModes {
+		t.Run(mode.String(), func(t *testing.T) {
+			clock := supervise.NewFakeClock(epoch)
+			network := faults.NewNetwork(1, faults.WithClock(clock),
+				faults.Latency(60*time.Millisecond, 60*time.Millisecond))
+			stop := make(chan struct{})
+			out := make(chan *supervise.Message, 10)
+
+			done, errs := supervise.ConnectionSteward(stop, network,
+				20*time.Millisecond, supervise.WithMessages(out),
+				supervise.WithReadMode(mode), supervise.WithDrain(time.Second),
+				supervise.WithClock(clock))
+
+			// Stop in the middle of the first read, which waits on
+			// the clock beside the ward's ticker, and let it finish
+			// once the drain deadline is waiting too.
+			clock.BlockUntil(1)
+			clock.Advance(10 * time.Millisecond)
+			clock.BlockUntil(2)
+			close(stop)
+			clock.BlockUntil(3)
+			clock.Advance(60 * time.Millisecond)
+			<-done
+
+			for err := range errs {
+				t.Errorf("got error %v", err)
+			}
+			if got := len(out); got != 1 {
+				t.Errorf("delivered %d messages, want 1", got)
+			}
+		})
+	}
+}
+
+func TestConnectionStewardAbandonsAHungReadAfterTheDrainDeadline(t *testing.T) {
+	const deadline = 50 * time.Millisecond
+
+	for _, mode := range readModes {
+		t.Run(mode.String(), func(t *testing.T) {
+			clock := supervise.NewFakeClock(epoch)
+			network := faults.NewNetwork(1, faults.WithClock(clock),
+				faults.Latency(time.Hour, time.Hour))
+			stop := make(chan struct{})
+			events := make(chan supervise.Event, 100)
+
+			done, errs := supervise.ConnectionSteward(stop, network,
+				time.Millisecond, supervise.WithReadMode(mode),
+				supervise.WithDrain(deadline), supervise.WithEvents(events),
+				supervise.WithClock(clock))
+
+			// The read in flight waits on the clock beside the
+			// ward's ticker, and so does the drain deadline once
+			// the steward is stopped.
+			clock.BlockUntil(1)
+			clock.Advance(time.Millisecond)
+			clock.BlockUntil(2)
+			close(stop)
+			clock.BlockUntil(3)
+
+			clock.Advance(deadline - time.Millisecond)
+			select {
+			case <-done:
+				t.Fatal("stopped before the drain deadline")
+			default:
+			}
+			clock.Advance(time.Millisecond)
+			<-done
+
+			var de *supervise.DrainError
+			if err := <-errs; !errors.As(err, &de) {
+				t.Fatalf("got error %v, want a *DrainError", err)
+			}
+			if want := (supervise.DrainError{Deadline: deadline, InFlight: true}); *de != want {
+				t.Errorf("got %+v, want %+v", *de, want)
+			}
+
+			close(events)
+			var last supervise.Event
+			for e := range events {
+				last = e
+			}
+			if !errors.Is(last.Err, supervise.ErrDrainTimeout) {
+				t.Errorf("last event %v, want one for %v", last, supervise.ErrDrainTimeout)
+			}
+		})
+	}
+}
+
+func TestConnectionStewardFlushesBufferedMessagesWhenDraining(t *testing.T) {
+	for _, size := range []int{3, 100} {
+		t.Run(strconv.Itoa(size), func(t *testing.T) {
+			network := &countedNetwork{Connector: faults.NewNetwork(1)}
+			stop := make(chan struct{})
+			out := make(chan *supervise.Message)
+
+			done, errs := supervise.ConnectionSteward(stop, network,
+				time.Millisecond, supervise.WithMessages(out),
+				supervise.WithOverflow(supervise.SpillToDisk, size),
+				supervise.WithSpillDir(t.TempDir()),
+				supervise.WithDrain(time.Second))
+
+			// Nothing is received until the steward is stopped.
+			eventually(t, "reads", func() bool { return network.reads.Load() >= 5 })
+			close(stop)
+
+			var got []string
+			for {
+				select {
+				case m := <-out:
+					got = append(got, m.Content)
+					continue
+				case <-done:
+				}
+				break
+			}
+
+			for err := range errs {
+				t.Errorf("got error %v", err)
+			}
+			if len(got) < 5 {
+				t.Fatalf("delivered %d messages, want at least 5", len(got))
+			}
+			for i, content := range got {
+				if want := strconv.Itoa(i + 1); content != want {
+					t.Fatalf("message %d is %q, want %q", i, content, want)
+				}
+			}
+		})
+	}
+}
+
+func TestConnectionStewardReportsMessagesAbandonedByTheDrain(t *testing.T) {
+	const deadline = 20 * time.Millisecond
+	network := &countedNetwork{Connector: faults.NewNetwork(1)}
+	stop := make(chan struct{})
+	out := make(chan *supervise.Message)
+
+	done, errs := supervise.ConnectionSteward(stop, network, time.Millisecond,
+		supervise.WithMessages(out),
+		supervise.WithOverflow(supervise.DropNewest, 100),
+		supervise.WithDrain(deadline))
+
+	// Nothing is ever received, so every read after the first waits in
+	// the buffer.
+	eventually(t, "reads", func() bool { return network.reads.Load() >= 3 })
+	close(stop)
+	<-done
+
+	var de *supervise.DrainError
+	if err := <-errs; !errors.As(err, &de) {
+		t.Fatalf("got error %v, want a *DrainError", err)
+	}
+	if de.Deadline != deadline || de.InFlight || de.Buffered == 0 {
+		t.Errorf("got %+v, want buffered messages abandoned after %v", *de, deadline)
+	}
+}
+
+func TestConnectionStewardWithoutDrainStopsAtOnce(t *testing.T) {
+	network := faults.NewNetwork(1, faults.Script(faults.Hung()))
+	stop := make(chan struct{})
+
+	done, errs := supervise.ConnectionSteward(stop, network, time.Millisecond)
+
+	eventually(t, "a connection", func() bool { return network.Attempts() == 1 })
+	close(stop)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("steward did not stop")
+	}
+	for err := range errs {
+		t.Errorf("got error %v", err)
+	}
+}
+
+func TestDrainErrorReportsWhatWasAbandoned(t *testing.T) {
+	tests := []struct {
+		err  supervise.DrainError
+		want string
+	}{
+		{
+			supervise.DrainError{Deadline: time.Second, Buffered: 3},
+			"supervise: drain timed out after 1s: abandoned 3 buffered messages",
+		},
+		{
+			supervise.DrainError{Deadline: time.Second, InFlight: true},
+			"supervise: drain timed out after 1s: abandoned an in-flight read and 0 buffered messages",
+		},
+	}
+
+	for _, tt := range tests {
+		if got := tt.err.Error(); got != tt.want {
+			t.Errorf("Error() = %q, want %q", got, tt.want)
+		}
+		if !errors.Is(&tt.err, supervise.ErrDrainTimeout) {
+			t.Errorf("%v does not match ErrDrainTimeout", &tt.err)
+		}
+	}
+}
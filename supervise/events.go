@@ -0,0 +1,127 @@
+package supervise
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// EventKind identifies a step in the lifecycle of a steward.
+type EventKind int
+
+const (
+	// Connecting is sent before every attempt to connect.
+	Connecting EventKind = iota + 1
+
+	// ConnectFailed is sent when an attempt to connect fails.
+	ConnectFailed
+
+	// Connected is sent when an attempt to connect succeeds.
+	Connected
+
+	// WardStarted is sent when a ward starts reading a new connection.
+	WardStarted
+
+	// WardUnhealthy is sent when the monitor judges a ward unhealthy, or
+	// with ErrRestartRequested when a Control forces a restart.
+	WardUnhealthy
+
+	// WardStopped is sent once a ward has stopped reading.
+	WardStopped
+
+	// ConnectionClosed is sent once the connection of a ward is closed, with
+	// a *CloseError if it failed to close or timed out.
+	ConnectionClosed
+
+	// StewardStopped is the last event sent by a steward.  It carries the
+	// error that made the steward give up or, failing that, a *DrainError
+	// if draining did not finish in time.
+	StewardStopped
+
+	// Paused is sent once a Control has paused the steward, which then
+	// has no ward and no connection.
+	Paused
+
+	// Resumed is sent when a Control resumes a paused steward.
+	Resumed
+
+	// FailedOver is sent when a steward moves to its next endpoint, with
+	// the error that made it move.
+	FailedOver
+
+	// FailedBack is sent when a steward connects to an endpoint it prefers
+	// to the current one and moves back to it.
+	FailedBack
+)
+
+func (k EventKind) String() string {
+	switch k {
+	case Connecting:
+		return "connecting"
+	case ConnectFailed:
+		return "connect failed"
+	case Connected:
+		return "connected"
+	case WardStarted:
+		return "ward started"
+	case WardUnhealthy:
+		return "ward unhealthy"
+	case WardStopped:
+		return "ward stopped"
+	case ConnectionClosed:
+		return "connection closed"
+	case StewardStopped:
+		return "steward stopped"
+	case Paused:
+		return "paused"
+	case Resumed:
+		return "resumed"
+	case FailedOver:
+		return "failed over"
+	case FailedBack:
+		return "failed back"
+	default:
+		return "unknown"
+	}
+}
+
+// Event describes a step in the lifecycle of a steward.
+type Event struct {
+	Kind EventKind
+	At   time.Time
+
+	// Attempt counts the steward's attempts to connect, from 1.
+	Attempt int
+
+	// Generation counts the wards started by the steward, from 1, and is
+	// zero before the first ward starts.
+	Generation int
+
+	// Endpoint is the index of the endpoint the steward connects to, where
+	// zero is the network it was started with and the fallbacks given by
+	// WithFailover follow.
+	Endpoint int
+
+	// Err is the cause of a ConnectFailed, WardUnhealthy, FailedOver or
+	// StewardStopped event, if any.
+	Err error
+}
+
+func (e Event) String() string {
+	s := fmt.Sprintf("%s (attempt %d, generation %d)", e.Kind, e.Attempt, e.Generation)
+	if e.Err != nil {
+		s += ": " + e.Err.Error()
+	}
+	return s
+}
+
+// EventCounters counts the events dropped by a steward.  It is safe for
+// concurrent use.
+type EventCounters struct {
+	dropped atomic.Uint64
+}
+
+// Dropped returns the number of events dropped.
+func (c *EventCounters) Dropped() uint64 {
+	return c.dropped.Load()
+}
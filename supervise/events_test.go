@@ -0,0 +1,128 @@
+package supervise_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mstreet3/go-blogs/supervise"
+	"github.com/mstreet3/go-blogs/supervise/faults"
+)
+
+func TestConnectionStewardSendsItsLifecycleEvents(t *testing.T) {
+	network := faults.NewNetwork(1,
+		faults.ConnectScript(faults.ErrConnectRefused),
+		faults.Script(faults.OK("1"), faults.Fail(supervise.ErrFatalSocketError)))
+	stop := make(chan struct{})
+	defer close(stop)
+	events := make(chan supervise.Event)
+
+	done, errs := supervise.ConnectionSteward(stop, network, time.Millisecond,
+		supervise.WithEvents(events), supervise.WithRestartIntensity(1, time.Minute))
+	go func() {
+		for range errs {
+		}
+	}()
+
+	var got []supervise.Event
+	for e := range receiveUntil(events, done) {
+		got = append(got, e)
+	}
+
+	type step struct {
+		kind       supervise.EventKind
+		attempt    int
+		generation int
+		err        error
+	}
+	want := []step{
+		{supervise.Connecting, 1, 0, nil},
+		{supervise.ConnectFailed, 1, 0, faults.ErrConnectRefused},
+		{supervise.Connecting, 2, 0, nil},
+		{supervise.Connected, 2, 0, nil},
+		{supervise.WardStarted, 2, 1, nil},
+		{supervise.WardUnhealthy, 2, 1, supervise.ErrFatalSocketError},
+		{supervise.WardStopped, 2, 1, nil},
+		{supervise.ConnectionClosed, 2, 1, nil},
+		{supervise.StewardStopped, 2, 1, supervise.ErrFatalSocketError},
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("got events %v, want %d events", got, len(want))
+	}
+	for i, e := range got {
+		w := want[i]
+		if e.Kind != w.kind || e.Attempt != w.attempt || e.Generation != w.generation ||
+			(w.err == nil) != (e.Err == nil) || !errors.Is(e.Err, w.err) {
+			t.Errorf("event %d is %v, want %s (attempt %d, generation %d) with %v",
+				i, e, w.kind, w.attempt, w.generation, w.err)
+		}
+		if e.At.IsZero() {
+			t.Errorf("event %d has no time", i)
+		}
+	}
+
+	var ie *supervise.RestartIntensityError
+	if last := got[len(got)-1]; !errors.As(last.Err, &ie) {
+		t.Errorf("steward stopped with %v, want a *RestartIntensityError", last.Err)
+	}
+}
+
+// receiveUntil forwards events until done is closed.
+func receiveUntil(events <-chan supervise.Event, done <-chan struct{}) <-chan supervise.Event {
+	out := make(chan supervise.Event)
+
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case e := <-events:
+				out <- e
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return out
+}
+
+func TestConnectionStewardDropsEventsOnceStopped(t *testing.T) {
+	network := faults.NewNetwork(1)
+	events := make(chan supervise.Event)
+	counters := new(supervise.EventCounters)
+	stop := make(chan struct{})
+
+	done, _ := supervise.ConnectionSteward(stop, network, time.Millisecond,
+		supervise.WithEvents(events), supervise.WithEventCounters(counters))
+
+	// The steward waits for its listener while it runs.
+	for _, want := range []supervise.EventKind{supervise.Connecting, supervise.Connected} {
+		if e := <-events; e.Kind != want {
+			t.Fatalf("got event %v, want %v", e, want)
+		}
+	}
+	if got := counters.Dropped(); got != 0 {
+		t.Errorf("dropped %d events while running, want 0", got)
+	}
+
+	// Once stopped, it drops the events its listener is not ready for.
+	close(stop)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("steward waited for a listener that stopped reading")
+	}
+	if counters.Dropped() == 0 {
+		t.Error("dropped no events once stopped")
+	}
+}
+
+func TestEventKindString(t *testing.T) {
+	if got := supervise.FailedBack.String(); got != "failed back" {
+		t.Errorf("FailedBack.String() = %q", got)
+	}
+	if got := supervise.EventKind(0).String(); got != "unknown" {
+		t.Errorf("EventKind(0).String() = %q", got)
+	}
+}
@@ -0,0 +1,147 @@
+package supervise
+
+import (
+	"context"
+	"log/slog"
+)
+
+// probeResult is the outcome of probing the endpoints a steward prefers.  A
+// failed probe has no connection.
+type probeResult struct {
+	endpoint int
+	conn     Conn
+}
+
+// dial connects to the current endpoint or hands over the connection that a
+// fail-back probe made to it.
+func (s *steward) dial() (Conn, error) {
+	if conn := s.handoff; conn != nil {
+		s.handoff = nil
+		return conn, nil
+	}
+	return connect(s.ctx, s.endpoints[s.endpoint])
+}
+
+// failover moves the steward on to its next endpoint, wrapping around to the
+// first, because of err.
+func (s *steward) failover(err error) {
+	s.connectFailures = 0
+	if len(s.endpoints) == 1 {
+		return
+	}
+
+	s.endpoint = (s.endpoint + 1) % len(s.endpoints)
+	s.logger.Warn("failing over", "endpoint", s.endpoint, "err", err)
+	s.emit(FailedOver, err)
+}
+
+// probe starts probing the endpoints the steward prefers unless w is already
+// probing them.  The connection a probe makes outlives w once it is handed
+// over, so the probe runs under the steward's context rather than w's.
+func (s *steward) probe(w *wardRun) {
+	if w.probing {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	w.probing, w.cancelProbe = true, cancel
+	go probe(ctx, cancel, s.endpoints[:s.endpoint], w.probed)
+}
+
+// failBack returns why w must stop for the steward to fail back to the
+// endpoint reached by p, or nil if the probe reached none.
+func (s *steward) failBack(w *wardRun, p probeResult) *wardExit {
+	w.probing = false
+	if p.conn == nil {
+		return nil
+	}
+
+	w.logger.Info("failing back", "endpoint", p.endpoint)
+	return &wardExit{failback: &p}
+}
+
+// stopProbes stops probing for w once it is done.  A probe still in flight
+// is cancelled and waited on for up to the close timeout, and any connection
+// it made is closed.  A probe that outlasts the timeout, because its
+// Connector ignores cancellation, is abandoned and its connection closed
+// whenever it is made.
+func (s *steward) stopProbes(w *wardRun) {
+	w.stopTicks()
+	if !w.probing {
+		return
+	}
+
+	w.probing = false
+	w.cancelProbe()
+
+	timer := s.o.clock.NewTimer(s.closeTimeout)
+	defer timer.Stop()
+
+	select {
+	case p := <-w.probed:
+		if p.conn != nil {
+			s.closeConn(p.conn)
+		}
+	case <-timer.C():
+		w.logger.Warn("abandoning fail-back probe", "timeout", s.closeTimeout)
+		go closeProbed(w.probed, w.logger)
+	}
+}
+
+// closeProbed closes the connection made by an abandoned probe, if it makes
+// one, once its result arrives on probed.
+func closeProbed(probed <-chan probeResult, logger *slog.Logger) {
+	p := <-probed
+	if p.conn == nil {
+		return
+	}
+
+	if err := p.conn.Close(); err != nil {
+		logger.Warn("failed to close abandoned probe's connection", "err", err)
+	}
+}
+
+// probe tries to connect to each of endpoints in order and sends the first
+// connection made on results, which must have room for it, or a result
+// without a connection if none was.  Closing the connection cancels ctx with
+// cancel, which is otherwise cancelled at once.
+func probe(
+	ctx context.Context, cancel context.CancelFunc, endpoints []Connector,
+	results chan<- probeResult,
+) {
+	for i, network := range endpoints {
+		conn, err := connect(ctx, network)
+		if err == nil {
+			results <- probeResult{endpoint: i, conn: probedConn{
+				Reader: Abandonable(conn),
+				conn:   conn,
+				cancel: cancel,
+			}}
+			return
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	cancel()
+	results <- probeResult{endpoint: -1}
+}
+
+// probedConn is a connection made by a fail-back probe, which cancels the
+// probe's context once it is closed.  Its Reader is always a ContextReader,
+// as made by Abandonable.
+type probedConn struct {
+	Reader
+	conn   Conn
+	cancel context.CancelFunc
+}
+
+func (c probedConn) ReadContext(ctx context.Context) (*Message, error) {
+	return c.Reader.(ContextReader).ReadContext(ctx)
+}
+
+func (c probedConn) Close() error {
+	defer c.cancel()
+	return c.conn.Close()
+}
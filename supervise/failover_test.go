@@ -0,0 +1,287 @@
+package supervise_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mstreet3/go-blogs/supervise"
+	"github.com/mstreet3/go-blogs/supervise/faults"
+)
+
+func TestConnectionStewardFailsBackToAContextBoundConnection(t *testing.T) {
+	// The primary is down until up is set, then streams events until the
+	// steward disconnects.
+	var up atomic.Bool
+	var streams atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !up.Load() {
+			http.Error(w, "down", http.StatusServiceUnavailable)
+			return
+		}
+
+		streams.Add(1)
+		w.Header().Set("Content-Type", "text/event-stream")
+		for {
+			fmt.Fprint(w, "data: primary\n\n")
+			w.(http.Flusher).Flush()
+
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(time.Millisecond):
+			}
+		}
+	}))
+	defer srv.Close()
+
+	secondary := faults.NewNetwork(1)
+	stop := make(chan struct{})
+	out := make(chan *supervise.Message)
+
+	done, errs := supervise.ConnectionSteward(stop, supervise.SSE(srv.URL),
+		time.Millisecond, supervise.WithMessages(out),
+		supervise.WithFailover([]supervise.Connector{secondary}, 1),
+		supervise.WithFailback(10*time.Millisecond))
+	go func() {
+		for range errs {
+		}
+	}()
+
+	// Read from the secondary before the primary comes back.
+	for i := 0; i < 3; i++ {
+		if m := <-out; m.Content == "primary" {
+			t.Fatal("read from the primary while it was down")
+		}
+	}
+	up.Store(true)
+
+	// The connection handed over by the probe must outlive the ward that
+	// probed, so the steward keeps reading from the primary.
+	timeout := time.After(time.Second)
+	for read := 0; read < 10; {
+		select {
+		case m := <-out:
+			if m.Content == "primary" {
+				read++
+			}
+		case <-timeout:
+			t.Fatalf("read %d messages from the primary, want 10", read)
+		}
+	}
+
+	close(stop)
+	<-done
+
+	if got := streams.Load(); got != 1 {
+		t.Errorf("opened %d streams from the primary, want 1", got)
+	}
+	if opened, closed := secondary.Attempts(), secondary.Closes(); opened != closed {
+		t.Errorf("closed %d of %d connections to the secondary", closed, opened)
+	}
+}
+
+func TestConnectionStewardFailsOverAfterFailedConnections(t *testing.T) {
+	primary := faults.NewNetwork(1, faults.ConnectFailures(nil, 1))
+	secondary := faults.NewNetwork(2)
+	tracker := supervise.NewStatusTracker(1)
+	events := make(chan supervise.Event, 100)
+	stop := make(chan struct{})
+
+	done, _ := supervise.ConnectionSteward(stop, primary, time.Millisecond,
+		supervise.WithFailover([]supervise.Connector{secondary}, 2),
+		supervise.WithStatus(tracker), supervise.WithEvents(events))
+
+	eventually(t, "a ward reading from the secondary", func() bool {
+		s := tracker.Status()
+		return s.State == supervise.StateRunning && s.Endpoint == 1
+	})
+	close(stop)
+	<-done
+
+	if got := primary.Attempts(); got != 2 {
+		t.Errorf("connected to the primary %d times, want 2", got)
+	}
+	if got := secondary.Attempts(); got != 1 {
+		t.Errorf("connected to the secondary %d times, want 1", got)
+	}
+
+	close(events)
+	var overs []supervise.Event
+	for e := range events {
+		if e.Kind == supervise.FailedOver {
+			overs = append(overs, e)
+		}
+	}
+	if len(overs) != 1 || overs[0].Endpoint != 1 ||
+		!errors.Is(overs[0].Err, faults.ErrConnectRefused) {
+		t.Errorf("failed over with %v, want once to endpoint 1 for %v",
+			overs, faults.ErrConnectRefused)
+	}
+}
+
+func TestConnectionStewardFailsOverFromAnUnhealthyWardAndBack(t *testing.T) {
+	// Every ward reading from the primary fails on its second read.
+	primary := faults.NewNetwork(1, faults.Script(faults.OK("a"),
+		faults.Fail(supervise.ErrFatalSocketError)))
+	secondary := faults.NewNetwork(2)
+	events := make(chan supervise.Event, 1000)
+	stop := make(chan struct{})
+
+	done, _ := supervise.ConnectionSteward(stop, primary, time.Millisecond,
+		supervise.WithFailover([]supervise.Connector{secondary}, 0),
+		supervise.WithFailback(20*time.Millisecond), supervise.WithEvents(events))
+
+	var got []string
+	eventually(t, "a fail-back", func() bool {
+		for {
+			select {
+			case e := <-events:
+				switch e.Kind {
+				case supervise.FailedOver, supervise.FailedBack:
+					got = append(got, fmt.Sprintf("%v to %d", e.Kind, e.Endpoint))
+				}
+				if e.Kind == supervise.FailedBack {
+					return true
+				}
+			default:
+				return false
+			}
+		}
+	})
+	close(stop)
+	<-done
+
+	want := []string{
+		fmt.Sprintf("%v to 1", supervise.FailedOver),
+		fmt.Sprintf("%v to 0", supervise.FailedBack),
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	if opened, closed := primary.Attempts(), primary.Closes(); opened != closed {
+		t.Errorf("closed %d of %d connections to the primary", closed, opened)
+	}
+	if opened, closed := secondary.Attempts(), secondary.Closes(); opened != closed {
+		t.Errorf("closed %d of %d connections to the secondary", closed, opened)
+	}
+}
+
+// gatedNetwork refuses its first connection and makes the others wait for
+// release, whatever the steward's context, to return conn.
+type gatedNetwork struct {
+	connects atomic.Int32
+	release  chan struct{}
+	conn     *faults.Reader
+}
+
+func (n *gatedNetwork) Connect() (supervise.Conn, error) {
+	if n.connects.Add(1) == 1 {
+		return nil, faults.ErrConnectRefused
+	}
+
+	<-n.release
+	return n.conn, nil
+}
+
+func TestConnectionStewardClosesTheConnectionOfACancelledProbe(t *testing.T) {
+	errClose := errors.New("close failed")
+	primary := &gatedNetwork{
+		release: make(chan struct{}),
+		conn:    faults.NewReader(1, faults.CloseErrors(errClose, 1)),
+	}
+	secondary := faults.NewNetwork(1)
+	var buf syncBuffer
+	events := make(chan supervise.Event, 1000)
+	stop := make(chan struct{})
+
+	done, errs := supervise.ConnectionSteward(stop, primary, time.Millisecond,
+		supervise.WithFailover([]supervise.Connector{secondary}, 1),
+		supervise.WithFailback(time.Millisecond), supervise.WithEvents(events),
+		supervise.WithCloseTimeout(time.Second),
+		supervise.WithLogger(slog.New(slog.NewTextHandler(&buf, nil))))
+
+	var stewardErrs []error
+	collected := make(chan struct{})
+	go func() {
+		defer close(collected)
+		for err := range errs {
+			stewardErrs = append(stewardErrs, err)
+		}
+	}()
+
+	// Stop the steward while a probe of the primary is connecting, and
+	// let the probe connect once the steward has cancelled it.
+	eventually(t, "a probe", func() bool { return primary.connects.Load() == 2 })
+	close(stop)
+	eventually(t, "the steward to stop its ward", func() bool {
+		return bytes.Contains(buf.Bytes(), []byte("received shutdown signal"))
+	})
+	close(primary.release)
+	<-done
+	<-collected
+
+	// The first error is the refused connection that made the steward
+	// fail over.
+	var ce *supervise.CloseError
+	if len(stewardErrs) != 2 || !errors.As(stewardErrs[1], &ce) ||
+		!errors.Is(ce, errClose) {
+		t.Errorf("got errors %v, want a *CloseError for %v last", stewardErrs, errClose)
+	}
+
+	close(events)
+	closeEvents := 0
+	for e := range events {
+		if e.Kind == supervise.ConnectionClosed && errors.Is(e.Err, errClose) {
+			closeEvents++
+		}
+	}
+	if closeEvents != 1 {
+		t.Errorf("sent %d events for failed closes, want 1", closeEvents)
+	}
+}
+
+func TestConnectionStewardAbandonsAProbeThatOutlastsTheCloseTimeout(t *testing.T) {
+	primary := &gatedNetwork{
+		release: make(chan struct{}),
+		conn:    faults.NewReader(1),
+	}
+	secondary := faults.NewNetwork(1)
+	stop := make(chan struct{})
+
+	done, errs := supervise.ConnectionSteward(stop, primary, time.Millisecond,
+		supervise.WithFailover([]supervise.Connector{secondary}, 1),
+		supervise.WithFailback(time.Millisecond),
+		supervise.WithCloseTimeout(10*time.Millisecond))
+	go func() {
+		for range errs {
+		}
+	}()
+
+	// The probe never connects while the steward waits for it, so the
+	// steward can only stop by abandoning it.
+	eventually(t, "a probe", func() bool { return primary.connects.Load() == 2 })
+	close(stop)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("steward waited for a probe that ignores cancellation")
+	}
+
+	close(primary.release)
+	eventually(t, "the probe's connection to close", func() bool {
+		_, err := primary.conn.Read()
+		return errors.Is(err, faults.ErrClosed)
+	})
+	if opened, closed := secondary.Attempts(), secondary.Closes(); opened != closed {
+		t.Errorf("closed %d of %d connections to the secondary", closed, opened)
+	}
+}
@@ -0,0 +1,200 @@
+// Package faults provides seeded, fault-injecting implementations of
+// supervise.Conn and supervise.Connector for testing stewards and the workers
+// built on them.
+//
+// Every source of randomness is drawn from a seed, so that a failing run can
+// be replayed exactly.  The eventuallyFatal reader of the "Healing Unhealthy
+// Goroutines" article becomes
+//
+//	faults.NewReader(seed, faults.Fatal(supervise.ErrFatalSocketError, 0.25))
+package faults
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mstreet3/go-blogs/supervise"
+)
+
+var (
+	// ErrClosed is returned by reads from a closed Reader, including reads
+	// that were hanging or waiting out their latency when it was closed.
+	ErrClosed = errors.New("faults: reader closed")
+
+	// ErrConnectRefused is the default error of failed connections.
+	ErrConnectRefused = errors.New("faults: connection refused")
+
+	// ErrNetworkDown is returned by connections and reads while a flapping
+	// network is down.  It wraps supervise.ErrFatalSocketError.
+	ErrNetworkDown = fmt.Errorf("%w: network down", supervise.ErrFatalSocketError)
+)
+
+// Step is the scripted outcome of a single read.  The zero Step reads a
+// message whose content is the number of the read.
+type Step struct {
+	// Content is the content of the message read, when not empty.
+	Content string
+
+	// Err fails the read.
+	Err error
+
+	// Latency delays the read.
+	Latency time.Duration
+
+	// Hang blocks the read until the Reader is closed.
+	Hang bool
+}
+
+// OK is a step that reads a message with content.
+func OK(content string) Step {
+	return Step{Content: content}
+}
+
+// Fail is a step that fails with err.
+func Fail(err error) Step {
+	return Step{Err: err}
+}
+
+// Hung is a step that blocks until the Reader is closed.
+func Hung() Step {
+	return Step{Hang: true}
+}
+
+// Option configures a Reader or a Network.  A Network passes its options on
+// to the readers of its connections.
+type Option func(*config)
+
+// errorClass fails reads with err at probability p.
+type errorClass struct {
+	err    error
+	p      float64
+	sticky bool
+}
+
+type config struct {
+	clock      supervise.Clock
+	script     []Step
+	classes    []errorClass
+	minLatency time.Duration
+	maxLatency time.Duration
+	hang       float64
+	connects   []error
+	connectErr error
+	connectP   float64
+	closeErr   error
+	closeP     float64
+	closeDelay time.Duration
+	up, down   time.Duration
+}
+
+func newConfig(opts []Option) config {
+	c := config{clock: supervise.SystemClock()}
+
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	return c
+}
+
+// WithClock makes latency, hangs and flapping follow c, such as a
+// supervise.FakeClock.
+func WithClock(c supervise.Clock) Option {
+	return func(cfg *config) {
+		if c != nil {
+			cfg.clock = c
+		}
+	}
+}
+
+// Script plays steps, in order, before any random outcome.  Every connection
+// of a Network replays the script from its start.
+func Script(steps ...Step) Option {
+	return func(cfg *config) {
+		cfg.script = append(cfg.script, steps...)
+	}
+}
+
+// Errors fails each read with err at probability p.  Classes are drawn in the
+// order given, so each is tried only on reads the earlier ones spared.
+func Errors(err error, p float64) Option {
+	return func(cfg *config) {
+		cfg.classes = append(cfg.classes, errorClass{err: err, p: p})
+	}
+}
+
+// Fatal behaves like Errors except that once a read fails with err every
+// later read fails with err too.
+func Fatal(err error, p float64) Option {
+	return func(cfg *config) {
+		cfg.classes = append(cfg.classes, errorClass{err: err, p: p, sticky: true})
+	}
+}
+
+// Latency delays every unscripted read by a duration drawn uniformly from
+// [min, max].
+func Latency(min, max time.Duration) Option {
+	return func(cfg *config) {
+		cfg.minLatency, cfg.maxLatency = min, max
+	}
+}
+
+// Hangs blocks each unscripted read at probability p until the Reader is
+// closed, like a legacy socket stuck in a read.
+func Hangs(p float64) Option {
+	return func(cfg *config) {
+		cfg.hang = p
+	}
+}
+
+// ConnectScript gives the outcome of the first connections of a Network in
+// order, where a nil error succeeds.
+func ConnectScript(errs ...error) Option {
+	return func(cfg *config) {
+		cfg.connects = append(cfg.connects, errs...)
+	}
+}
+
+// ConnectFailures fails each unscripted connection of a Network with err at
+// probability p.  A nil err fails with ErrConnectRefused.
+func ConnectFailures(err error, p float64) Option {
+	return func(cfg *config) {
+		if err == nil {
+			err = ErrConnectRefused
+		}
+		cfg.connectErr, cfg.connectP = err, p
+	}
+}
+
+// CloseErrors fails each Close at probability p with err.
+func CloseErrors(err error, p float64) Option {
+	return func(cfg *config) {
+		cfg.closeErr, cfg.closeP = err, p
+	}
+}
+
+// CloseLatency makes each Close take d after releasing any read hanging on
+// the connection, like a connection that is slow to shut down.
+func CloseLatency(d time.Duration) Option {
+	return func(cfg *config) {
+		cfg.closeDelay = d
+	}
+}
+
+// Flapping takes the network up for up and then down for down, repeatedly,
+// starting when the Reader or Network is created.  While the network is down
+// connections and reads fail with ErrNetworkDown.
+func Flapping(up, down time.Duration) Option {
+	return func(cfg *config) {
+		cfg.up, cfg.down = up, down
+	}
+}
+
+// isDown reports whether a network flapping since start is down at now.
+func (cfg *config) isDown(start, now time.Time) bool {
+	if cfg.up <= 0 || cfg.down <= 0 {
+		return false
+	}
+	return now.Sub(start)%(cfg.up+cfg.down) >= cfg.up
+}
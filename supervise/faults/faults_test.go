@@ -0,0 +1,184 @@
+package faults_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mstreet3/go-blogs/supervise"
+	"github.com/mstreet3/go-blogs/supervise/faults"
+)
+
+// outcome is the result of a read as a comparable value.
+type outcome struct {
+	content string
+	err     error
+}
+
+func read(r *faults.Reader) outcome {
+	msg, err := r.Read()
+	if err != nil {
+		return outcome{err: err}
+	}
+	return outcome{content: msg.Content}
+}
+
+func TestReaderReplaysItsSeed(t *testing.T) {
+	errFlaky := errors.New("flaky")
+	opts := []faults.Option{
+		faults.Errors(errFlaky, 0.3),
+		faults.Fatal(supervise.ErrFatalSocketError, 0.05),
+	}
+	a := faults.NewReader(7, opts...)
+	b := faults.NewReader(7, opts...)
+
+	for i := 0; i < 100; i++ {
+		if got, want := read(a), read(b); got != want {
+			t.Fatalf("read %d: %+v, want %+v", i, got, want)
+		}
+	}
+}
+
+func TestReaderPlaysItsScriptFirst(t *testing.T) {
+	errBroken := errors.New("broken")
+	r := faults.NewReader(1, faults.Script(faults.OK("a"), faults.Fail(errBroken),
+		faults.Step{}), faults.Errors(errBroken, 0))
+
+	want := []outcome{{content: "a"}, {err: errBroken}, {content: "3"}, {content: "4"}}
+	for i, w := range want {
+		if got := read(r); got != w {
+			t.Errorf("read %d: %+v, want %+v", i, got, w)
+		}
+	}
+	if got := r.Reads(); got != len(want) {
+		t.Errorf("Reads() = %d, want %d", got, len(want))
+	}
+}
+
+func TestReaderFatalErrorsStick(t *testing.T) {
+	r := faults.NewReader(1, faults.Fatal(supervise.ErrFatalSocketError, 0.5))
+
+	failed := false
+	for i := 0; i < 100; i++ {
+		o := read(r)
+		if failed && o.err != supervise.ErrFatalSocketError {
+			t.Fatalf("read %d after a fatal error got %+v", i, o)
+		}
+		failed = failed || o.err != nil
+	}
+	if !failed {
+		t.Fatal("never failed")
+	}
+}
+
+func TestReaderCloseReleasesAHungRead(t *testing.T) {
+	errClose := errors.New("close")
+	r := faults.NewReader(1, faults.Script(faults.Hung()),
+		faults.CloseErrors(errClose, 1))
+
+	released := make(chan error)
+	go func() {
+		_, err := r.Read()
+		released <- err
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	if err := r.Close(); err != errClose {
+		t.Errorf("Close() = %v, want %v", err, errClose)
+	}
+	if err := r.Close(); err != nil {
+		t.Errorf("second Close() = %v, want nil", err)
+	}
+
+	select {
+	case err := <-released:
+		if err != faults.ErrClosed {
+			t.Errorf("hung read returned %v, want %v", err, faults.ErrClosed)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Close did not release the hung read")
+	}
+	if _, err := r.Read(); err != faults.ErrClosed {
+		t.Errorf("read after Close returned %v, want %v", err, faults.ErrClosed)
+	}
+}
+
+func TestReaderLatencyFollowsItsClock(t *testing.T) {
+	clock := supervise.NewFakeClock(time.Unix(0, 0))
+	r := faults.NewReader(1, faults.Latency(time.Second, time.Second),
+		faults.WithClock(clock))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		r.Read()
+	}()
+
+	clock.BlockUntil(1)
+	select {
+	case <-done:
+		t.Fatal("read returned before its latency passed")
+	default:
+	}
+
+	clock.Advance(time.Second)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("read did not return once its latency passed")
+	}
+}
+
+func TestNetworkFlaps(t *testing.T) {
+	clock := supervise.NewFakeClock(time.Unix(0, 0))
+	n := faults.NewNetwork(1, faults.Flapping(time.Second, time.Second),
+		faults.WithClock(clock))
+
+	r, err := n.Connect()
+	if err != nil {
+		t.Fatalf("connect while up: %v", err)
+	}
+
+	clock.Advance(1500 * time.Millisecond)
+	if _, err := n.Connect(); !errors.Is(err, supervise.ErrFatalSocketError) {
+		t.Errorf("connect while down got %v, want %v", err, faults.ErrNetworkDown)
+	}
+	if _, err := r.Read(); err != faults.ErrNetworkDown {
+		t.Errorf("read while down got %v, want %v", err, faults.ErrNetworkDown)
+	}
+
+	clock.Advance(time.Second)
+	if _, err := n.Connect(); err != nil {
+		t.Errorf("connect once up again: %v", err)
+	}
+}
+
+func TestNetworkCountsConnectionsAndCloses(t *testing.T) {
+	n := faults.NewNetwork(1, faults.ConnectScript(faults.ErrConnectRefused, nil),
+		faults.ConnectFailures(nil, 0))
+
+	if _, err := n.Connect(); err != faults.ErrConnectRefused {
+		t.Errorf("first connect got %v, want %v", err, faults.ErrConnectRefused)
+	}
+
+	conn, err := n.Connect()
+	if err != nil {
+		t.Fatalf("second connect: %v", err)
+	}
+	conn.Close()
+	conn.Close()
+
+	if got := n.Attempts(); got != 2 {
+		t.Errorf("Attempts() = %d, want 2", got)
+	}
+	if got := n.Closes(); got != 1 {
+		t.Errorf("Closes() = %d, want 1", got)
+	}
+}
+
+func TestNetworkConnectFailures(t *testing.T) {
+	n := faults.NewNetwork(1, faults.ConnectFailures(nil, 1))
+	if _, err := n.Connect(); err != faults.ErrConnectRefused {
+		t.Errorf("connect got %v, want %v", err, faults.ErrConnectRefused)
+	}
+}
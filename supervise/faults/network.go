@@ -0,0 +1,86 @@
+package faults
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/mstreet3/go-blogs/supervise"
+)
+
+// Network is a supervise.Connector that injects faults into its connections,
+// each of which is a fresh Reader.  It is safe for concurrent use.
+type Network struct {
+	cfg   config
+	start time.Time
+
+	mu       sync.Mutex
+	rng      *rand.Rand
+	connects []error
+	attempts int
+	closes   int
+}
+
+// NewNetwork returns a Network whose random outcomes, including those of the
+// readers of its connections, are drawn from seed.
+func NewNetwork(seed int64, opts ...Option) *Network {
+	cfg := newConfig(opts)
+
+	return &Network{
+		cfg:      cfg,
+		start:    cfg.clock.Now(),
+		rng:      rand.New(rand.NewSource(seed)),
+		connects: cfg.connects,
+	}
+}
+
+// Connect plays the next scripted connection outcome or draws a random one
+// and, on success, returns a new Reader.
+func (n *Network) Connect() (supervise.Conn, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.attempts++
+
+	if len(n.connects) > 0 {
+		err := n.connects[0]
+		n.connects = n.connects[1:]
+		if err != nil {
+			return nil, err
+		}
+		return n.open(), nil
+	}
+
+	if n.cfg.isDown(n.start, n.cfg.clock.Now()) {
+		return nil, ErrNetworkDown
+	}
+
+	if n.cfg.connectP > 0 && n.rng.Float64() < n.cfg.connectP {
+		return nil, n.cfg.connectErr
+	}
+
+	return n.open(), nil
+}
+
+// open returns a new connection.  The caller must hold n.mu.
+func (n *Network) open() *Reader {
+	return newReader(n.rng.Int63(), n.cfg, n.start, func() {
+		n.mu.Lock()
+		defer n.mu.Unlock()
+		n.closes++
+	})
+}
+
+// Attempts returns the number of calls to Connect so far.
+func (n *Network) Attempts() int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.attempts
+}
+
+// Closes returns the number of connections closed so far.
+func (n *Network) Closes() int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.closes
+}
@@ -0,0 +1,178 @@
+package faults
+
+import (
+	"math/rand"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/mstreet3/go-blogs/supervise"
+)
+
+// Reader is a supervise.Conn that injects faults.  Its reads may be made
+// from one goroutine while another closes it.
+type Reader struct {
+	cfg     config
+	start   time.Time
+	onClose func()
+
+	mu     sync.Mutex
+	rng    *rand.Rand
+	script []Step
+	sticky error
+	reads  int
+
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// NewReader returns a Reader whose random outcomes are drawn from seed.
+func NewReader(seed int64, opts ...Option) *Reader {
+	return newReader(seed, newConfig(opts), time.Time{}, nil)
+}
+
+// newReader returns a Reader configured by cfg that calls onClose, if not nil,
+// when it is closed.  A reader of a Network shares the start of the network's
+// flapping; otherwise it starts its own.
+func newReader(seed int64, cfg config, start time.Time, onClose func()) *Reader {
+	if start.IsZero() {
+		start = cfg.clock.Now()
+	}
+
+	return &Reader{
+		cfg:     cfg,
+		start:   start,
+		onClose: onClose,
+		rng:     rand.New(rand.NewSource(seed)),
+		script:  cfg.script,
+		closed:  make(chan struct{}),
+	}
+}
+
+// Read plays the next scripted step or draws a random one.
+func (r *Reader) Read() (*supervise.Message, error) {
+	return r.play(r.next())
+}
+
+// Reads returns the number of reads made so far.
+func (r *Reader) Reads() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.reads
+}
+
+// Close releases every hanging read and fails every later read with
+// ErrClosed.  The first Close then takes the latency and may fail as
+// configured; later calls return nil at once.
+func (r *Reader) Close() error {
+	var err error
+
+	r.closeOnce.Do(func() {
+		close(r.closed)
+
+		if r.onClose != nil {
+			r.onClose()
+		}
+
+		r.mu.Lock()
+		if r.cfg.closeP > 0 && r.rng.Float64() < r.cfg.closeP {
+			err = r.cfg.closeErr
+		}
+		r.mu.Unlock()
+
+		if r.cfg.closeDelay > 0 {
+			r.cfg.clock.Sleep(r.cfg.closeDelay)
+		}
+	})
+
+	return err
+}
+
+// next decides the outcome of the next read, numbering the message of a
+// step without content.
+func (r *Reader) next() Step {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.reads++
+
+	step := r.draw()
+	if step.Content == "" {
+		step.Content = strconv.Itoa(r.reads)
+	}
+
+	return step
+}
+
+// draw pops the next scripted step or draws a random one.  The caller must
+// hold r.mu.
+func (r *Reader) draw() Step {
+	if len(r.script) > 0 {
+		step := r.script[0]
+		r.script = r.script[1:]
+		return step
+	}
+
+	if r.cfg.isDown(r.start, r.cfg.clock.Now()) {
+		return Fail(ErrNetworkDown)
+	}
+
+	if r.sticky != nil {
+		return Fail(r.sticky)
+	}
+
+	var step Step
+	if span := r.cfg.maxLatency - r.cfg.minLatency; span > 0 {
+		step.Latency = r.cfg.minLatency + time.Duration(r.rng.Int63n(int64(span)+1))
+	} else {
+		step.Latency = r.cfg.minLatency
+	}
+
+	if r.cfg.hang > 0 && r.rng.Float64() < r.cfg.hang {
+		step.Hang = true
+		return step
+	}
+
+	for _, class := range r.cfg.classes {
+		if r.rng.Float64() < class.p {
+			if class.sticky {
+				r.sticky = class.err
+			}
+			step.Err = class.err
+			return step
+		}
+	}
+
+	return step
+}
+
+// play carries out step, giving up with ErrClosed if the reader is closed
+// first.
+func (r *Reader) play(step Step) (*supervise.Message, error) {
+	select {
+	case <-r.closed:
+		return nil, ErrClosed
+	default:
+	}
+
+	if step.Latency > 0 {
+		timer := r.cfg.clock.NewTimer(step.Latency)
+		select {
+		case <-r.closed:
+			timer.Stop()
+			return nil, ErrClosed
+		case <-timer.C():
+		}
+	}
+
+	if step.Hang {
+		<-r.closed
+		return nil, ErrClosed
+	}
+
+	if step.Err != nil {
+		return nil, step.Err
+	}
+
+	return &supervise.Message{Content: step.Content}, nil
+}
@@ -0,0 +1,212 @@
+package supervise
+
+import (
+	"errors"
+	"time"
+)
+
+// HealthPolicy decides from the reads of a ward whether the ward is
+// unhealthy.  A monitor resets its policy before it starts and then calls it
+// from a single goroutine, so a policy must not be shared by workers that run
+// at the same time; WithHealthPolicyFunc gives each its own.
+//
+// A monitor only observes successful reads when it receives the ward's
+// heartbeats, which a steward always arranges.
+type HealthPolicy interface {
+	// Failure records a failed read and reports whether the ward is now
+	// unhealthy.
+	Failure(at time.Time, err error) bool
+
+	// Success records a successful read.
+	Success(at time.Time)
+
+	// Reset forgets every observation, ready to watch a new ward.
+	Reset()
+}
+
+// HealthFunc adapts a function that judges a single error to a HealthPolicy.
+type HealthFunc func(error) bool
+
+// Failure returns f(err).
+func (f HealthFunc) Failure(_ time.Time, err error) bool {
+	return f(err)
+}
+
+// Success does nothing.
+func (f HealthFunc) Success(time.Time) {}
+
+// Reset does nothing.
+func (f HealthFunc) Reset() {}
+
+// ConsecutiveErrors is unhealthy once n reads in a row have failed.
+func ConsecutiveErrors(n int) HealthPolicy {
+	return &consecutiveErrors{n: n}
+}
+
+type consecutiveErrors struct {
+	n, failed int
+}
+
+func (p *consecutiveErrors) Failure(time.Time, error) bool {
+	p.failed++
+	return p.failed >= p.n
+}
+
+func (p *consecutiveErrors) Success(time.Time) {
+	p.failed = 0
+}
+
+func (p *consecutiveErrors) Reset() {
+	p.failed = 0
+}
+
+// ErrorRate is unhealthy once at least threshold, between 0 and 1, of the
+// reads within the trailing window have failed.  The rate is only judged
+// once the window holds at least minReads reads.
+func ErrorRate(threshold float64, window time.Duration, minReads int) HealthPolicy {
+	return &errorRate{
+		threshold: threshold,
+		window:    window,
+		minReads:  minReads,
+	}
+}
+
+type outcome struct {
+	at     time.Time
+	failed bool
+}
+
+type errorRate struct {
+	threshold float64
+	window    time.Duration
+	minReads  int
+	reads     []outcome
+	failed    int
+}
+
+func (p *errorRate) observe(at time.Time, failed bool) {
+	p.reads = append(p.reads, outcome{at: at, failed: failed})
+	if failed {
+		p.failed++
+	}
+
+	expired := 0
+	for expired < len(p.reads) && at.Sub(p.reads[expired].at) > p.window {
+		if p.reads[expired].failed {
+			p.failed--
+		}
+		expired++
+	}
+	p.reads = p.reads[expired:]
+}
+
+func (p *errorRate) Failure(at time.Time, _ error) bool {
+	p.observe(at, true)
+
+	if len(p.reads) < p.minReads || len(p.reads) == 0 {
+		return false
+	}
+
+	return float64(p.failed)/float64(len(p.reads)) >= p.threshold
+}
+
+func (p *errorRate) Success(at time.Time) {
+	p.observe(at, false)
+}
+
+func (p *errorRate) Reset() {
+	p.reads = nil
+	p.failed = 0
+}
+
+// AnyOf is unhealthy as soon as any of policies is.  Every policy observes
+// every read.
+func AnyOf(policies ...HealthPolicy) HealthPolicy {
+	return anyOf(policies)
+}
+
+type anyOf []HealthPolicy
+
+func (ps anyOf) Failure(at time.Time, err error) bool {
+	unhealthy := false
+	for _, p := range ps {
+		if p.Failure(at, err) {
+			unhealthy = true
+		}
+	}
+	return unhealthy
+}
+
+func (ps anyOf) Success(at time.Time) {
+	for _, p := range ps {
+		p.Success(at)
+	}
+}
+
+func (ps anyOf) Reset() {
+	for _, p := range ps {
+		p.Reset()
+	}
+}
+
+// AllOf is unhealthy once every one of policies is unhealthy on the same
+// failed read.  Every policy observes every read.
+func AllOf(policies ...HealthPolicy) HealthPolicy {
+	return allOf(policies)
+}
+
+type allOf []HealthPolicy
+
+func (ps allOf) Failure(at time.Time, err error) bool {
+	unhealthy := len(ps) > 0
+	for _, p := range ps {
+		if !p.Failure(at, err) {
+			unhealthy = false
+		}
+	}
+	return unhealthy
+}
+
+func (ps allOf) Success(at time.Time) {
+	for _, p := range ps {
+		p.Success(at)
+	}
+}
+
+func (ps allOf) Reset() {
+	for _, p := range ps {
+		p.Reset()
+	}
+}
+
+// ForClass applies policy only to failures caused by an error matching
+// target, as reported by errors.Is, along with every successful read.
+// Combined with AnyOf it gives each class of error its own threshold:
+//
+//	AnyOf(
+//		ForClass(ErrFatalSocketError, ConsecutiveErrors(1)),
+//		ForClass(io.ErrUnexpectedEOF, ConsecutiveErrors(5)),
+//	)
+func ForClass(target error, policy HealthPolicy) HealthPolicy {
+	return &forClass{target: target, policy: policy}
+}
+
+type forClass struct {
+	target error
+	policy HealthPolicy
+}
+
+func (p *forClass) Failure(at time.Time, err error) bool {
+	if !errors.Is(err, p.target) {
+		return false
+	}
+	return p.policy.Failure(at, err)
+}
+
+func (p *forClass) Success(at time.Time) {
+	p.policy.Success(at)
+}
+
+func (p *forClass) Reset() {
+	p.policy.Reset()
+}
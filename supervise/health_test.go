@@ -0,0 +1,228 @@
+package supervise_test
+
+import (
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/mstreet3/go-blogs/supervise"
+	"github.com/mstreet3/go-blogs/supervise/faults"
+)
+
+// read is an observation fed to a HealthPolicy: a failure if err is set and a
+// success otherwise.
+type read struct {
+	after time.Duration
+	err   error
+}
+
+// verdict is an observation passed to a HealthPolicy: a failure if err is set
+// and a success otherwise, along with whether the policy found it unhealthy.
+type verdict struct {
+	err       error
+	unhealthy bool
+}
+
+// observing passes observations on to a HealthPolicy and reports every one
+// on seen, which must have room for them.
+type observing struct {
+	supervise.HealthPolicy
+	seen chan verdict
+}
+
+func (o observing) Failure(at time.Time, err error) bool {
+	unhealthy := o.HealthPolicy.Failure(at, err)
+	o.seen <- verdict{err: err, unhealthy: unhealthy}
+	return unhealthy
+}
+
+func (o observing) Success(at time.Time) {
+	o.HealthPolicy.Success(at)
+	o.seen <- verdict{}
+}
+
+func TestHealthPolicies(t *testing.T) {
+	errTransient := errors.New("transient")
+	fatal := supervise.ErrFatalSocketError
+	eof := io.ErrUnexpectedEOF
+
+	tests := []struct {
+		name   string
+		policy supervise.HealthPolicy
+		reads  []read
+		want   []bool
+	}{
+		{
+			name:   "consecutive errors",
+			policy: supervise.ConsecutiveErrors(2),
+			reads:  []read{{err: errTransient}, {}, {err: errTransient}, {err: errTransient}},
+			want:   []bool{false, false, false, true},
+		},
+		{
+			name:   "error rate below minimum reads",
+			policy: supervise.ErrorRate(0.5, time.Second, 4),
+			reads:  []read{{err: errTransient}, {err: errTransient}, {err: errTransient}},
+			want:   []bool{false, false, false},
+		},
+		{
+			name:   "error rate",
+			policy: supervise.ErrorRate(0.5, time.Second, 4),
+			reads:  []read{{}, {}, {err: errTransient}, {err: errTransient}},
+			want:   []bool{false, false, false, true},
+		},
+		{
+			name:   "error rate forgets reads outside the window",
+			policy: supervise.ErrorRate(0.5, time.Second, 2),
+			reads:  []read{{err: errTransient}, {after: 2 * time.Second}, {}, {err: errTransient}},
+			want:   []bool{false, false, false, false},
+		},
+		{
+			name:   "for class ignores other errors",
+			policy: supervise.ForClass(fatal, supervise.ConsecutiveErrors(1)),
+			reads:  []read{{err: errTransient}, {err: fatal}},
+			want:   []bool{false, true},
+		},
+		{
+			name: "any of",
+			policy: supervise.AnyOf(
+				supervise.ForClass(fatal, supervise.ConsecutiveErrors(1)),
+				supervise.ForClass(eof, supervise.ConsecutiveErrors(2)),
+			),
+			reads: []read{{err: eof}, {err: eof}, {}, {err: fatal}},
+			want:  []bool{false, true, false, true},
+		},
+		{
+			name: "all of",
+			policy: supervise.AllOf(
+				supervise.ConsecutiveErrors(1),
+				supervise.ConsecutiveErrors(2),
+			),
+			reads: []read{{err: errTransient}, {err: errTransient}},
+			want:  []bool{false, true},
+		},
+		{
+			name:   "all of nothing",
+			policy: supervise.AllOf(),
+			reads:  []read{{err: errTransient}},
+			want:   []bool{false},
+		},
+		{
+			name: "health func",
+			policy: supervise.HealthFunc(func(err error) bool {
+				return errors.Is(err, fatal)
+			}),
+			reads: []read{{err: errTransient}, {err: fatal}},
+			want:  []bool{false, true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			at := time.Unix(0, 0)
+			tt.policy.Reset()
+
+			for i, r := range tt.reads {
+				at = at.Add(r.after)
+
+				got := false
+				if r.err == nil {
+					tt.policy.Success(at)
+				} else {
+					got = tt.policy.Failure(at, r.err)
+				}
+
+				if got != tt.want[i] {
+					t.Errorf("read %d: unhealthy = %v, want %v", i, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestHealthPolicyReset(t *testing.T) {
+	p := supervise.ConsecutiveErrors(2)
+	p.Failure(time.Unix(0, 0), supervise.ErrFatalSocketError)
+	p.Reset()
+
+	if p.Failure(time.Unix(0, 0), supervise.ErrFatalSocketError) {
+		t.Error("unhealthy after a reset and a single failure")
+	}
+}
+
+func TestConnectionStewardRestartsByItsHealthPolicy(t *testing.T) {
+	errTransient := errors.New("transient")
+
+	tests := []struct {
+		name    string
+		policy  supervise.HealthPolicy
+		steps   []faults.Step
+		restart bool
+	}{
+		{
+			// Heartbeats are dropped while the monitor is busy, so
+			// the errors are kept several reads apart.
+			"errors between reads", supervise.ConsecutiveErrors(2),
+			[]faults.Step{
+				faults.Fail(errTransient), faults.OK("a"), faults.OK("b"), faults.OK("c"),
+				faults.Fail(errTransient), faults.OK("d"), faults.OK("e"), faults.OK("f"),
+				faults.Fail(errTransient), faults.Hung(),
+			},
+			false,
+		},
+		{
+			"error rate", supervise.ErrorRate(0.5, time.Minute, 4),
+			[]faults.Step{
+				faults.Fail(errTransient), faults.OK("a"),
+				faults.Fail(errTransient), faults.OK("b"),
+				faults.Fail(errTransient), faults.Hung(),
+			},
+			true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			network := faults.NewNetwork(1, faults.Script(tt.steps...))
+			stop := make(chan struct{})
+			policy := observing{tt.policy, make(chan verdict, len(tt.steps))}
+
+			done, errs := supervise.ConnectionSteward(stop, network,
+				4*time.Millisecond, supervise.WithHealthPolicy(policy))
+			go func() {
+				for range errs {
+				}
+			}()
+
+			if tt.restart {
+				go func() {
+					for range policy.seen {
+					}
+				}()
+				eventually(t, "a restart", func() bool { return network.Attempts() >= 2 })
+				close(stop)
+				<-done
+				close(policy.seen)
+				return
+			}
+
+			// The ward hangs once it has read every step, and only a
+			// failure the policy finds unhealthy restarts it.
+			for failures := 0; failures < 3; {
+				v := <-policy.seen
+				if v.unhealthy {
+					t.Fatalf("unhealthy after %v", v.err)
+				}
+				if v.err != nil {
+					failures++
+				}
+			}
+
+			close(stop)
+			<-done
+			if got := network.Attempts(); got != 1 {
+				t.Errorf("connected %d times, want 1", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package supervise
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// RestartIntensityError is the terminal error of a worker that restarted more
+// than MaxRestarts times within Window and gave up.
+type RestartIntensityError struct {
+	MaxRestarts int
+	Window      time.Duration
+
+	// Causes holds the cause of every restart within Window, oldest first.
+	Causes []error
+}
+
+func (e *RestartIntensityError) Error() string {
+	causes := make([]string, len(e.Causes))
+	for i, c := range e.Causes {
+		causes[i] = c.Error()
+	}
+
+	return fmt.Sprintf(
+		"supervise: restart intensity exceeded: %d restarts within %v "+
+			"(max %d); causes: %s",
+		len(e.Causes), e.Window, e.MaxRestarts, strings.Join(causes, "; "),
+	)
+}
+
+// Unwrap returns the causes so that errors.Is and errors.As match any of
+// them.
+func (e *RestartIntensityError) Unwrap() []error {
+	return e.Causes
+}
+
+type restart struct {
+	at    time.Time
+	cause error
+}
+
+// restartIntensity tracks the restarts of a worker within a sliding window.
+type restartIntensity struct {
+	max      int
+	window   time.Duration
+	restarts []restart
+}
+
+// record notes a restart at the given time and returns a
+// *RestartIntensityError if the restart exhausted the budget.
+func (ri *restartIntensity) record(at time.Time, cause error) error {
+	if ri.max < 0 {
+		return nil
+	}
+
+	ri.restarts = append(ri.restarts, restart{at: at, cause: cause})
+
+	expired := 0
+	for expired < len(ri.restarts) && at.Sub(ri.restarts[expired].at) > ri.window {
+		expired++
+	}
+	ri.restarts = ri.restarts[expired:]
+
+	if len(ri.restarts) <= ri.max {
+		return nil
+	}
+
+	causes := make([]error, len(ri.restarts))
+	for i, r := range ri.restarts {
+		causes[i] = r.cause
+	}
+
+	return &RestartIntensityError{
+		MaxRestarts: ri.max,
+		Window:      ri.window,
+		Causes:      causes,
+	}
+}
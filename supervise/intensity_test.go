@@ -0,0 +1,104 @@
+package supervise
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRestartIntensityForgetsRestartsOutsideTheWindow(t *testing.T) {
+	start := time.Unix(0, 0)
+	ri := &restartIntensity{max: 2, window: time.Second}
+	errA, errB, errC := errors.New("a"), errors.New("b"), errors.New("c")
+
+	if err := ri.record(start, errA); err != nil {
+		t.Fatalf("first restart gave up: %v", err)
+	}
+	if err := ri.record(start.Add(500*time.Millisecond), errB); err != nil {
+		t.Fatalf("second restart gave up: %v", err)
+	}
+
+	// The first restart has left the window by the time of the third.
+	if err := ri.record(start.Add(1200*time.Millisecond), errC); err != nil {
+		t.Fatalf("third restart gave up: %v", err)
+	}
+
+	err := ri.record(start.Add(1300*time.Millisecond), errA)
+	var ie *RestartIntensityError
+	if !errors.As(err, &ie) {
+		t.Fatalf("fourth restart got %v, want a *RestartIntensityError", err)
+	}
+	if len(ie.Causes) != 3 || ie.Causes[0] != errB || ie.Causes[2] != errA {
+		t.Errorf("got causes %v, want [b c a]", ie.Causes)
+	}
+	if !errors.Is(err, errC) {
+		t.Error("error does not match a cause within the window")
+	}
+}
+
+func TestRestartIntensityIsUnlimitedByDefault(t *testing.T) {
+	ri := &restartIntensity{max: -1}
+	for i := 0; i < 100; i++ {
+		if err := ri.record(time.Unix(0, 0), ErrFatalSocketError); err != nil {
+			t.Fatalf("restart %d gave up: %v", i+1, err)
+		}
+	}
+}
+
+// brokenNetwork connects to readers that always fail, or fails to connect if
+// err is set.
+type brokenNetwork struct {
+	err error
+}
+
+func (n brokenNetwork) Connect() (Conn, error) {
+	if n.err != nil {
+		return nil, n.err
+	}
+	return brokenConn{}, nil
+}
+
+type brokenConn struct{}
+
+func (brokenConn) Read() (*Message, error) { return nil, ErrFatalSocketError }
+func (brokenConn) Close() error            { return nil }
+
+func TestConnectionStewardGivesUpOnceItsRestartIntensityIsExceeded(t *testing.T) {
+	errRefused := errors.New("refused")
+
+	tests := []struct {
+		name    string
+		network Connector
+		cause   error
+	}{
+		{"unhealthy wards", brokenNetwork{}, ErrFatalSocketError},
+		{"failed connections", brokenNetwork{err: errRefused}, errRefused},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stop := make(chan struct{})
+			defer close(stop)
+
+			done, errs := ConnectionSteward(stop, tt.network, time.Millisecond,
+				WithRestartIntensity(3, time.Minute))
+
+			var last error
+			for err := range errs {
+				last = err
+			}
+			<-done
+
+			var ie *RestartIntensityError
+			if !errors.As(last, &ie) {
+				t.Fatalf("got error %v, want a *RestartIntensityError", last)
+			}
+			if len(ie.Causes) != 4 {
+				t.Errorf("gave up after %d restarts, want 4", len(ie.Causes))
+			}
+			if !errors.Is(last, tt.cause) {
+				t.Errorf("error %v does not match %v", last, tt.cause)
+			}
+		})
+	}
+}
@@ -0,0 +1,21 @@
+package supervise
+
+import (
+	"context"
+	"log/slog"
+)
+
+// discardHandler drops every record, so that workers stay silent unless given
+// a logger with WithLogger.
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discardHandler) WithGroup(string) slog.Handler           { return h }
+
+// log returns the logger of a worker of the given kind, such as "ward" or
+// "steward", labelled with the worker's name.
+func (o *options) log(kind string) *slog.Logger {
+	return o.logger.With(slog.String("worker", o.name), slog.String("kind", kind))
+}
@@ -0,0 +1,80 @@
+package supervise_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mstreet3/go-blogs/supervise"
+	"github.com/mstreet3/go-blogs/supervise/faults"
+)
+
+// syncBuffer is a bytes.Buffer safe for concurrent use.
+type syncBuffer struct {
+	mu sync.Mutex
+	b  bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.b.Write(p)
+}
+
+func (b *syncBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return bytes.Clone(b.b.Bytes())
+}
+
+func TestConnectionStewardLogsWithItsName(t *testing.T) {
+	var buf syncBuffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	network := faults.NewNetwork(1, faults.Script(faults.OK("1"),
+		faults.Fail(supervise.ErrFatalSocketError), faults.Hung()))
+	stop := make(chan struct{})
+
+	done, _ := supervise.ConnectionSteward(stop, network, time.Millisecond,
+		supervise.WithLogger(logger), supervise.WithName("feed"))
+	eventually(t, "a restart", func() bool { return network.Attempts() >= 2 })
+	close(stop)
+	<-done
+
+	kinds := make(map[string]bool)
+	startedWard := false
+
+	dec := json.NewDecoder(bytes.NewReader(buf.Bytes()))
+	for dec.More() {
+		var record struct {
+			Msg        string
+			Worker     string
+			Kind       string
+			Attempt    int
+			Generation int
+		}
+		if err := dec.Decode(&record); err != nil {
+			t.Fatal(err)
+		}
+
+		if record.Worker != "feed" {
+			t.Errorf("logged %q for worker %q, want %q", record.Msg, record.Worker, "feed")
+		}
+		kinds[record.Kind] = true
+
+		if record.Msg == "starting ward" && record.Attempt == 1 && record.Generation == 1 {
+			startedWard = true
+		}
+	}
+
+	for _, kind := range []string{"steward", "ward", "monitor"} {
+		if !kinds[kind] {
+			t.Errorf("nothing logged by the %s", kind)
+		}
+	}
+	if !startedWard {
+		t.Error("did not log the attempt and generation of the first ward")
+	}
+}
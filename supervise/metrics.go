@@ -0,0 +1,223 @@
+package supervise
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrorClass returns a short, stable name for the kind of err that is used to
+// label error metrics.
+func ErrorClass(err error) string {
+	switch {
+	case err == nil:
+		return "none"
+	case errors.Is(err, ErrFatalSocketError):
+		return "fatal_socket"
+	case errors.Is(err, ErrStalled):
+		return "stalled"
+	case errors.Is(err, ErrReadTimeout):
+		return "read_timeout"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "deadline_exceeded"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	default:
+		return "other"
+	}
+}
+
+// Metrics records the reads of wards and the restarts of stewards and serves
+// them in the Prometheus text exposition format.  Every series is labelled by
+// the name given to the worker with WithName, and times are told by the clock
+// given to the worker with WithClock.  A Metrics is safe for concurrent use
+// and may be shared by any number of workers.
+type Metrics struct {
+	classify func(error) string
+
+	mu     sync.Mutex
+	series map[string]*workerMetrics
+}
+
+// NewMetrics returns an empty Metrics that labels read errors with classify,
+// or with ErrorClass if classify is nil.
+func NewMetrics(classify func(error) string) *Metrics {
+	if classify == nil {
+		classify = ErrorClass
+	}
+
+	return &Metrics{
+		classify: classify,
+		series:   make(map[string]*workerMetrics),
+	}
+}
+
+// workerMetrics holds the series of a single named worker.
+type workerMetrics struct {
+	reads           uint64
+	readErrors      map[string]uint64
+	restarts        uint64
+	connectFailures uint64
+	clock           Clock
+	wardStarted     time.Time
+	unhealthyAt     time.Time
+	healSeconds     float64
+	heals           uint64
+}
+
+// worker returns the series of the named worker, creating them on first use.
+// The caller must hold m.mu.
+func (m *Metrics) worker(name string) *workerMetrics {
+	w, ok := m.series[name]
+	if !ok {
+		w = &workerMetrics{readErrors: make(map[string]uint64)}
+		m.series[name] = w
+	}
+	return w
+}
+
+// read records the outcome of a read by a ward.
+func (m *Metrics) read(name string, err error) {
+	if m == nil {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	w := m.worker(name)
+	if err != nil {
+		w.readErrors[m.classify(err)]++
+		return
+	}
+	w.reads++
+}
+
+// event records a step in the lifecycle of a steward whose events are timed
+// by clock.  The ward uptime is measured by the same clock.
+func (m *Metrics) event(name string, e Event, clock Clock) {
+	if m == nil {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	w := m.worker(name)
+	switch e.Kind {
+	case ConnectFailed:
+		w.connectFailures++
+	case WardStarted:
+		w.clock, w.wardStarted = clock, e.At
+		if !w.unhealthyAt.IsZero() {
+			w.healSeconds += e.At.Sub(w.unhealthyAt).Seconds()
+			w.heals++
+			w.unhealthyAt = time.Time{}
+		}
+	case WardUnhealthy:
+		w.restarts++
+		w.unhealthyAt = e.At
+	case WardStopped:
+		w.wardStarted = time.Time{}
+	}
+}
+
+// ServeHTTP writes every series in the Prometheus text exposition format.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.WriteTo(w)
+}
+
+// WriteTo writes every series to w in the Prometheus text exposition format.
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	names := make([]string, 0, len(m.series))
+	for name := range m.series {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+
+	family := func(name, kind, help string, sample func(worker string, s *workerMetrics)) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+		for _, worker := range names {
+			sample(worker, m.series[worker])
+		}
+	}
+
+	family("supervise_reads_total", "counter",
+		"Messages read by wards.",
+		func(worker string, s *workerMetrics) {
+			fmt.Fprintf(&b, "supervise_reads_total{worker=%s} %d\n",
+				quote(worker), s.reads)
+		})
+
+	family("supervise_read_errors_total", "counter",
+		"Failed reads by wards by class of error.",
+		func(worker string, s *workerMetrics) {
+			classes := make([]string, 0, len(s.readErrors))
+			for class := range s.readErrors {
+				classes = append(classes, class)
+			}
+			sort.Strings(classes)
+
+			for _, class := range classes {
+				fmt.Fprintf(&b, "supervise_read_errors_total{worker=%s,class=%s} %d\n",
+					quote(worker), quote(class), s.readErrors[class])
+			}
+		})
+
+	family("supervise_ward_restarts_total", "counter",
+		"Unhealthy wards restarted by stewards.",
+		func(worker string, s *workerMetrics) {
+			fmt.Fprintf(&b, "supervise_ward_restarts_total{worker=%s} %d\n",
+				quote(worker), s.restarts)
+		})
+
+	family("supervise_connect_failures_total", "counter",
+		"Failed attempts of stewards to connect.",
+		func(worker string, s *workerMetrics) {
+			fmt.Fprintf(&b, "supervise_connect_failures_total{worker=%s} %d\n",
+				quote(worker), s.connectFailures)
+		})
+
+	family("supervise_ward_uptime_seconds", "gauge",
+		"Time since the current ward started, or zero without a ward.",
+		func(worker string, s *workerMetrics) {
+			uptime := 0.0
+			if !s.wardStarted.IsZero() {
+				uptime = s.clock.Now().Sub(s.wardStarted).Seconds()
+			}
+			fmt.Fprintf(&b, "supervise_ward_uptime_seconds{worker=%s} %g\n",
+				quote(worker), uptime)
+		})
+
+	family("supervise_time_to_heal_seconds", "summary",
+		"Time from a ward being judged unhealthy to its replacement starting.",
+		func(worker string, s *workerMetrics) {
+			fmt.Fprintf(&b, "supervise_time_to_heal_seconds_sum{worker=%s} %g\n",
+				quote(worker), s.healSeconds)
+			fmt.Fprintf(&b, "supervise_time_to_heal_seconds_count{worker=%s} %d\n",
+				quote(worker), s.heals)
+		})
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// labelEscaper escapes label values as the exposition format requires.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// quote returns v as a quoted label value.
+func quote(v string) string {
+	return `"` + labelEscaper.Replace(v) + `"`
+}
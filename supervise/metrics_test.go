@@ -0,0 +1,86 @@
+package supervise
+
+import (
+	"errors"
+	"flag"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+func TestMetricsServeHTTP(t *testing.T) {
+	clock := NewFakeClock(time.Unix(0, 0))
+	m := NewMetrics(nil)
+
+	event := func(name string, kind EventKind) {
+		m.event(name, Event{Kind: kind, At: clock.Now()}, clock)
+	}
+
+	// The first steward reads, fails, heals after 1.5s and has been
+	// reading for 2s since.
+	event("alpha", Connecting)
+	event("alpha", Connected)
+	event("alpha", WardStarted)
+	m.read("alpha", nil)
+	m.read("alpha", nil)
+	m.read("alpha", errors.New("transient"))
+	m.read("alpha", ErrFatalSocketError)
+	event("alpha", WardUnhealthy)
+	event("alpha", WardStopped)
+	clock.Advance(time.Second)
+	event("alpha", ConnectFailed)
+	clock.Advance(500 * time.Millisecond)
+	event("alpha", WardStarted)
+	m.read("alpha", nil)
+
+	// The second, whose name needs escaping, never connected.
+	event(`b"e\ta`, ConnectFailed)
+	event(`b"e\ta`, ConnectFailed)
+
+	clock.Advance(2 * time.Second)
+
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	if got, want := rec.Header().Get("Content-Type"), "text/plain; version=0.0.4; charset=utf-8"; got != want {
+		t.Errorf("Content-Type = %q, want %q", got, want)
+	}
+
+	golden := filepath.Join("testdata", "metrics.golden")
+	if *update {
+		if err := os.WriteFile(golden, rec.Body.Bytes(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := rec.Body.String(); got != string(want) {
+		t.Errorf("served\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestErrorClass(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{nil, "none"},
+		{ErrFatalSocketError, "fatal_socket"},
+		{ErrStalled, "stalled"},
+		{ErrReadTimeout, "read_timeout"},
+		{errors.New("other"), "other"},
+	}
+
+	for _, tt := range tests {
+		if got := ErrorClass(tt.err); got != tt.want {
+			t.Errorf("ErrorClass(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package supervise
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrStalled is the cause given to a ward that missed its heartbeats.
+var ErrStalled = errors.New("supervise: ward stalled")
+
+// Monitor closes its returned channel once policy reports an error read from
+// errs as unhealthy.  See MonitorContext.
+func Monitor(
+	stop <-chan struct{}, errs <-chan error, policy HealthPolicy,
+	opts ...Option,
+) <-chan struct{} {
+	ctx, release := stopContext(stop)
+	done := MonitorContext(ctx, errs, policy, opts...)
+	releaseWhenDone(done, release)
+	return done
+}
+
+// MonitorContext closes its returned channel once policy reports an error
+// read from errs as unhealthy, errs is closed or ctx is done.  Use HealthFunc
+// to monitor with a plain function.  Of the options only WithName, WithLogger
+// and WithClock apply.
+func MonitorContext(
+	ctx context.Context, errs <-chan error, policy HealthPolicy,
+	opts ...Option,
+) <-chan struct{} {
+	return signalDone(monitor(ctx, errs, nil, 0, policy, newOptions(opts)))
+}
+
+// MonitorHeartbeat behaves like Monitor and also closes its returned channel
+// when no heartbeat arrives within timeout.  See MonitorHeartbeatContext.
+func MonitorHeartbeat(
+	stop <-chan struct{}, errs <-chan error, heartbeat <-chan time.Time,
+	timeout time.Duration, policy HealthPolicy, opts ...Option,
+) <-chan struct{} {
+	ctx, release := stopContext(stop)
+	done := MonitorHeartbeatContext(ctx, errs, heartbeat, timeout, policy,
+		opts...)
+	releaseWhenDone(done, release)
+	return done
+}
+
+// MonitorHeartbeatContext behaves like MonitorContext and also closes its
+// returned channel when no heartbeat arrives within timeout, which catches a
+// ward that is stuck rather than failing.  Every heartbeat not preceded by an
+// error is observed by policy as a successful read.  A zero timeout disables
+// stall detection.
+func MonitorHeartbeatContext(
+	ctx context.Context, errs <-chan error, heartbeat <-chan time.Time,
+	timeout time.Duration, policy HealthPolicy, opts ...Option,
+) <-chan struct{} {
+	return signalDone(monitor(ctx, errs, heartbeat, timeout, policy,
+		newOptions(opts)))
+}
+
+// signalDone closes its returned channel once unhealthy is closed.
+func signalDone(unhealthy <-chan error) <-chan struct{} {
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		for range unhealthy {
+		}
+	}()
+
+	return done
+}
+
+// monitor is a routine with the single responsibility of sending the reason
+// the ward is unhealthy on its returned channel, and closing it, if its
+// policy judges the ward unhealthy or the ward misses its heartbeat.
+func monitor(
+	ctx context.Context, errs <-chan error, heartbeat <-chan time.Time,
+	timeout time.Duration, policy HealthPolicy, o *options,
+) <-chan error {
+
+	stop := ctx.Done()
+	logger := o.log("monitor")
+	unhealthy := make(chan error, 1)
+
+	// A zero timeout leaves stalled nil, so that it never fires.
+	var (
+		stalled <-chan time.Time
+		timer   Timer
+	)
+	if timeout > 0 {
+		timer = o.clock.NewTimer(timeout)
+		stalled = timer.C()
+	}
+
+	// failed records whether an error arrived since the last heartbeat.
+	failed := false
+
+	// observe passes an error to the policy and reports whether the ward
+	// is unhealthy, in which case the error is sent.
+	observe := func(e error) bool {
+		failed = true
+		if policy.Failure(o.clock.Now(), e) {
+			logger.Warn("ward is unhealthy", "err", e)
+			unhealthy <- e
+			return true
+		}
+		return false
+	}
+
+	go func() {
+		defer close(unhealthy)
+		defer logger.Debug("shutting down")
+
+		if timer != nil {
+			defer timer.Stop()
+		}
+
+		policy.Reset()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case at, ok := <-heartbeat:
+				if !ok {
+					heartbeat = nil
+					continue
+				}
+
+				// The ward sends any error from a read before
+				// the heartbeat that follows it, so observe
+				// pending errors first.
+				for pending := true; pending; {
+					select {
+					case e, ok := <-errs:
+						if !ok || observe(e) {
+							return
+						}
+					default:
+						pending = false
+					}
+				}
+
+				// Restart the stall timer before telling the
+				// policy, so that the timeout runs from every
+				// heartbeat the policy has seen.
+				if timer != nil {
+					if !timer.Stop() {
+						select {
+						case <-timer.C():
+						default:
+						}
+					}
+					timer.Reset(timeout)
+				}
+
+				if !failed {
+					policy.Success(at)
+				}
+				failed = false
+			case <-stalled:
+				err := fmt.Errorf("%w: no heartbeat within %v", ErrStalled, timeout)
+				logger.Warn("ward is unhealthy", "err", err)
+				unhealthy <- err
+				return
+			case e, ok := <-errs:
+				if !ok {
+					return
+				}
+
+				if observe(e) {
+					return
+				}
+			}
+		}
+	}()
+
+	return unhealthy
+}
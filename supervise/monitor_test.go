@@ -0,0 +1,67 @@
+package supervise_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mstreet3/go-blogs/supervise"
+)
+
+func TestMonitorSignalsOnceThePolicyFindsTheWardUnhealthy(t *testing.T) {
+	stop := make(chan struct{})
+	defer close(stop)
+	errs := make(chan error)
+
+	unhealthy := supervise.Monitor(stop, errs, supervise.ForClass(
+		supervise.ErrFatalSocketError, supervise.ConsecutiveErrors(1)))
+
+	errs <- errors.New("transient")
+	select {
+	case <-unhealthy:
+		t.Fatal("signalled on a transient error")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	errs <- supervise.ErrFatalSocketError
+	select {
+	case <-unhealthy:
+	case <-time.After(time.Second):
+		t.Fatal("did not signal on a fatal error")
+	}
+}
+
+func TestMonitorHeartbeatSignalsAStall(t *testing.T) {
+	clock := supervise.NewFakeClock(time.Unix(0, 0))
+	stop := make(chan struct{})
+	defer close(stop)
+	beats := make(chan time.Time)
+	policy := observing{supervise.ConsecutiveErrors(1), make(chan verdict)}
+
+	unhealthy := supervise.MonitorHeartbeat(stop, make(chan error), beats,
+		30*time.Millisecond, policy, supervise.WithClock(clock))
+
+	// Heartbeats within the timeout keep the ward healthy.  A heartbeat
+	// reaching the policy shows that the monitor has not stalled and that
+	// its timer runs again from the heartbeat.
+	clock.BlockUntil(1)
+	for _, d := range []time.Duration{20, 20, 20, 29} {
+		clock.Advance(d * time.Millisecond)
+		beats <- clock.Now()
+		<-policy.seen
+	}
+
+	clock.Advance(29 * time.Millisecond)
+	select {
+	case <-unhealthy:
+		t.Fatal("signalled before the timeout passed")
+	default:
+	}
+
+	clock.Advance(time.Millisecond)
+	select {
+	case <-unhealthy:
+	case <-time.After(time.Second):
+		t.Fatal("did not signal a stall")
+	}
+}
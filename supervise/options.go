@@ -0,0 +1,320 @@
+package supervise
+
+import (
+	"log/slog"
+	"time"
+)
+
+// Option configures a ward, a ConnectionSteward or a Supervisor.  Options
+// that do not apply to a worker are ignored, and a steward passes its options
+// on to the wards it starts.
+type Option func(*options)
+
+type options struct {
+	backoff       Backoff
+	backoffReset  time.Duration
+	maxRestarts   int
+	window        time.Duration
+	heartbeat     chan<- time.Time
+	stallPulses   int
+	readTimeout   time.Duration
+	readMode      ReadMode
+	closeTimeout  time.Duration
+	drain         time.Duration
+	draining      <-chan struct{}
+	health        HealthPolicy
+	newHealth     func() HealthPolicy
+	messages      chan<- *Message
+	overflow      OverflowPolicy
+	overflowSize  int
+	spillDir      string
+	counters      *OverflowCounters
+	outlet        *outlet
+	errCounters   *ErrorCounters
+	deadLetter    func(DeadLetter)
+	events        chan<- Event
+	eventCounters *EventCounters
+	name          string
+	metrics       *Metrics
+	status        *StatusTracker
+	control       *Control
+	fallbacks     []Connector
+	failAfter     int
+	probe         time.Duration
+	logger        *slog.Logger
+	clock         Clock
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{
+		maxRestarts: -1,
+		name:        "default",
+		logger:      slog.New(discardHandler{}),
+		clock:       SystemClock(),
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// WithBackoff sets the delay between connection attempts and between ward
+// restarts.  Without a Backoff a steward retries failed connections every
+// pulseInterval and restarts unhealthy wards immediately.  A supervisor
+// delays restarting its children by the Backoff and otherwise restarts them
+// immediately.
+func WithBackoff(b Backoff) Option {
+	return func(o *options) {
+		o.backoff = b
+	}
+}
+
+// WithBackoffReset sets how long a ward or child must stay healthy before
+// the backoff starts again from its first attempt.  The default is ten pulse
+// intervals for a steward and ten seconds for a supervisor.
+func WithBackoffReset(healthyFor time.Duration) Option {
+	return func(o *options) {
+		o.backoffReset = healthyFor
+	}
+}
+
+// WithRestartIntensity limits a steward to maxRestarts restarts within
+// window, counting both failed connections and restarts of unhealthy wards.
+// A steward that exceeds the limit gives up: it sends a
+// *RestartIntensityError on its error channel and closes done.  A supervisor
+// counts every restart of a failed child and stops all of its children
+// before giving up.  By default both restart forever.
+func WithRestartIntensity(maxRestarts int, window time.Duration) Option {
+	return func(o *options) {
+		o.maxRestarts = maxRestarts
+		o.window = window
+	}
+}
+
+// WithHeartbeat makes a ward send the time on beats after every read, without
+// blocking if beats is full.  A steward always replaces beats with its own
+// channel, which its monitor reads, so beats only applies to a ward started on
+// its own.
+func WithHeartbeat(beats chan<- time.Time) Option {
+	return func(o *options) {
+		o.heartbeat = beats
+	}
+}
+
+// WithStallDetection makes a steward restart a ward that sends no heartbeat
+// for pulses pulse intervals, such as a ward whose Read never returns.
+func WithStallDetection(pulses int) Option {
+	return func(o *options) {
+		o.stallPulses = pulses
+	}
+}
+
+// WithReadMode sets how a ward paces its reads.  The default is Poll.
+func WithReadMode(mode ReadMode) Option {
+	return func(o *options) {
+		o.readMode = mode
+	}
+}
+
+// WithReadTimeout fails every read that takes longer than timeout with
+// ErrReadTimeout.  A Reader that is not a ContextReader is adapted with
+// Abandonable so that the ward moves on from a stuck Read.  A steward treats
+// a timed-out read as unhealthy whatever its health policy and closes the
+// connection to release the abandoned Read.
+func WithReadTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.readTimeout = timeout
+	}
+}
+
+// WithCloseTimeout sets how long a steward waits for a connection to close
+// before it reports ErrCloseTimeout and moves on.  The default is ten pulse
+// intervals.
+func WithCloseTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.closeTimeout = timeout
+	}
+}
+
+// WithDrain makes a steward drain on shutdown: its ward starts no new reads
+// but finishes the read in flight, and the messages buffered by WithOverflow
+// are delivered, until deadline passes.  The steward then stops the ward and
+// sends a *DrainError reporting what it abandoned.  By default a steward
+// stops its ward at once and drops any buffered messages.
+func WithDrain(deadline time.Duration) Option {
+	return func(o *options) {
+		o.drain = deadline
+	}
+}
+
+// WithFailover gives a steward fallbacks, in order of preference, for the
+// network it was started with.  The steward moves to the next endpoint, from
+// the last back to the first, whenever its ward is unhealthy or connecting
+// fails connectFailures times in a row.  A connectFailures of zero moves on
+// after the first failure.  Wrap a fallback that is a ConnectCloser with
+// SharedClose before passing it.
+func WithFailover(fallbacks []Connector, connectFailures int) Option {
+	return func(o *options) {
+		o.fallbacks = fallbacks
+		o.failAfter = connectFailures
+	}
+}
+
+// WithFailback makes a steward that failed over try to connect to the
+// endpoints it prefers every interval while its ward is healthy.  The first
+// to connect replaces the current connection.  Without WithFailback a steward
+// only returns to an endpoint it prefers once every later one has failed.
+func WithFailback(interval time.Duration) Option {
+	return func(o *options) {
+		o.probe = interval
+	}
+}
+
+// WithHealthPolicy sets when a steward restarts a ward.  By default a ward is
+// restarted on its first ErrFatalSocketError.  A ConnectionPool ignores p,
+// since its stewards would share it; give it WithHealthPolicyFunc instead.
+func WithHealthPolicy(p HealthPolicy) Option {
+	return func(o *options) {
+		o.health, o.newHealth = p, nil
+	}
+}
+
+// WithHealthPolicyFunc is like WithHealthPolicy but makes every steward call
+// newPolicy for a HealthPolicy of its own, so that the stewards of a
+// ConnectionPool each judge their own wards.
+func WithHealthPolicyFunc(newPolicy func() HealthPolicy) Option {
+	return func(o *options) {
+		o.health, o.newHealth = nil, newPolicy
+	}
+}
+
+// healthPolicy returns a worker's HealthPolicy, or nil if none was given.
+func (o *options) healthPolicy() HealthPolicy {
+	if o.newHealth != nil {
+		return o.newHealth()
+	}
+	return o.health
+}
+
+// WithMessages makes a ward send every message it reads on out instead of
+// logging it.  A steward gives the same out to every ward it starts, so a
+// consumer keeps reading from out while wards are restarted underneath it.
+// Workers never close out; its owner may close it once the worker is done.
+// A ward waiting on a slow consumer sends no heartbeats.
+func WithMessages(out chan<- *Message) Option {
+	return func(o *options) {
+		o.messages = out
+	}
+}
+
+// WithOverflow sets what happens to messages read while the consumer given
+// by WithMessages is busy.  Every policy but Block holds up to size messages
+// in memory before it drops or spills messages.  The default is Block.
+func WithOverflow(policy OverflowPolicy, size int) Option {
+	return func(o *options) {
+		o.overflow = policy
+		o.overflowSize = size
+	}
+}
+
+// WithSpillDir sets the directory of the files used by SpillToDisk.  The
+// default is os.TempDir.
+func WithSpillDir(dir string) Option {
+	return func(o *options) {
+		o.spillDir = dir
+	}
+}
+
+// WithOverflowCounters makes the overflow policy count the messages it drops
+// or spills in c.
+func WithOverflowCounters(c *OverflowCounters) Option {
+	return func(o *options) {
+		o.counters = c
+	}
+}
+
+// WithErrorCounters makes workers count the errors they drop in c.
+func WithErrorCounters(c *ErrorCounters) Option {
+	return func(o *options) {
+		o.errCounters = c
+	}
+}
+
+// WithDeadLetter makes workers call sink with every error they drop, which
+// happens when nobody is ready to receive the error.  sink is called from the
+// worker's goroutine and must not block.
+func WithDeadLetter(sink func(DeadLetter)) Option {
+	return func(o *options) {
+		o.deadLetter = sink
+	}
+}
+
+// WithEvents makes a steward send every step of its lifecycle on events.  The
+// steward waits for each event to be received until it is stopped, and then
+// drops any event that is not received at once, so that a listener that stops
+// reading cannot keep it from finishing.  Workers never close events.
+func WithEvents(events chan<- Event) Option {
+	return func(o *options) {
+		o.events = events
+	}
+}
+
+// WithEventCounters makes a steward count in c the events it drops.
+func WithEventCounters(c *EventCounters) Option {
+	return func(o *options) {
+		o.eventCounters = c
+	}
+}
+
+// WithName names a worker in its metrics and logs.  The default name is
+// "default".
+func WithName(name string) Option {
+	return func(o *options) {
+		o.name = name
+	}
+}
+
+// WithMetrics makes a ward record its reads, and a steward its restarts, in
+// m.
+func WithMetrics(m *Metrics) Option {
+	return func(o *options) {
+		o.metrics = m
+	}
+}
+
+// WithStatus makes a steward keep its status in t.
+func WithStatus(t *StatusTracker) Option {
+	return func(o *options) {
+		o.status = t
+	}
+}
+
+// WithControl makes a steward obey the commands sent with c.
+func WithControl(c *Control) Option {
+	return func(o *options) {
+		o.control = c
+	}
+}
+
+// WithLogger makes a worker log to l.  Records carry the worker's name and
+// kind along with, for a steward and its wards, the connection attempt and
+// ward generation.  Workers are silent by default.
+func WithLogger(l *slog.Logger) Option {
+	return func(o *options) {
+		if l != nil {
+			o.logger = l
+		}
+	}
+}
+
+// WithClock makes a worker tell the time and schedule its ticks, timeouts and
+// backoff delays with c, such as a FakeClock in tests.
+func WithClock(c Clock) Option {
+	return func(o *options) {
+		if c != nil {
+			o.clock = c
+		}
+	}
+}
@@ -0,0 +1,294 @@
+package supervise
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"sync/atomic"
+)
+
+// OverflowPolicy decides what happens to messages read while a consumer is
+// too slow to receive them.
+type OverflowPolicy int
+
+const (
+	// Block makes the ward wait for the consumer, so that no message is
+	// lost but reads stall.
+	Block OverflowPolicy = iota
+
+	// DropNewest buffers messages and drops each message read while the
+	// buffer is full, keeping the oldest messages.
+	DropNewest
+
+	// DropOldest buffers messages in a ring and evicts the oldest message
+	// when a new one is read while the ring is full, keeping the freshest
+	// messages.
+	DropOldest
+
+	// SpillToDisk buffers messages and appends the overflow to a file,
+	// delivering every message in order once the consumer catches up.
+	SpillToDisk
+)
+
+func (p OverflowPolicy) String() string {
+	switch p {
+	case Block:
+		return "block"
+	case DropNewest:
+		return "drop-newest"
+	case DropOldest:
+		return "drop-oldest"
+	case SpillToDisk:
+		return "spill-to-disk"
+	default:
+		return "unknown"
+	}
+}
+
+// OverflowCounters counts the messages affected by an overflow policy.  It is
+// safe for concurrent use.
+type OverflowCounters struct {
+	dropped atomic.Uint64
+	spilled atomic.Uint64
+}
+
+// Dropped returns the number of messages dropped.
+func (c *OverflowCounters) Dropped() uint64 {
+	return c.dropped.Load()
+}
+
+// Spilled returns the number of messages spilled to disk.
+func (c *OverflowCounters) Spilled() uint64 {
+	return c.spilled.Load()
+}
+
+// outlet delivers the messages of one or more wards, in turn, to a consumer
+// according to an overflow policy.  Every policy but Block runs a goroutine
+// that holds the buffer, so that wards never wait for the consumer.
+type outlet struct {
+	out      chan<- *Message
+	policy   OverflowPolicy
+	size     int
+	spillDir string
+	counters *OverflowCounters
+	logger   *slog.Logger
+	in       chan *Message
+	flushing chan struct{}
+
+	// lost counts the messages still buffered when run returned.
+	lost int
+}
+
+func newOutlet(o *options) *outlet {
+	counters := o.counters
+	if counters == nil {
+		counters = new(OverflowCounters)
+	}
+
+	size := o.overflowSize
+	if size < 1 {
+		size = 1
+	}
+
+	return &outlet{
+		out:      o.messages,
+		policy:   o.overflow,
+		size:     size,
+		spillDir: o.spillDir,
+		counters: counters,
+		logger:   o.log("outlet"),
+		in:       make(chan *Message),
+		flushing: make(chan struct{}),
+	}
+}
+
+// send hands msg to the outlet and reports false if stop was closed first.
+func (l *outlet) send(stop <-chan struct{}, msg *Message) bool {
+	var dst chan<- *Message = l.in
+	if l.policy == Block {
+		dst = l.out
+	}
+
+	select {
+	case <-stop:
+		return false
+	case dst <- msg:
+		return true
+	}
+}
+
+// flush makes run return once every buffered message is delivered.  No
+// messages may be sent after flush is called.
+func (l *outlet) flush() {
+	close(l.flushing)
+}
+
+// abandoned returns the number of messages never delivered.  It must only be
+// called once run is done.
+func (l *outlet) abandoned() int {
+	return l.lost
+}
+
+// run buffers and delivers messages until ctx is done or, once flushed, the
+// buffer is empty.
+func (l *outlet) run(ctx context.Context) <-chan struct{} {
+	done := make(chan struct{})
+
+	if l.policy == Block {
+		close(done)
+		return done
+	}
+
+	stop := ctx.Done()
+	buf := make([]*Message, 0, l.size)
+	spill := &spillFile{dir: l.spillDir}
+
+	// push buffers msg, applying the overflow policy when the buffer is
+	// full.  Messages are spilled while older messages remain on disk so
+	// that delivery stays in order.
+	push := func(msg *Message) {
+		if len(buf) < l.size && spill.pending == 0 {
+			buf = append(buf, msg)
+			return
+		}
+
+		switch l.policy {
+		case DropNewest:
+			l.counters.dropped.Add(1)
+		case DropOldest:
+			buf = append(buf[1:], msg)
+			l.counters.dropped.Add(1)
+		case SpillToDisk:
+			if err := spill.write(msg); err != nil {
+				l.logger.Warn("dropped message; cannot spill", "err", err)
+				l.counters.dropped.Add(1)
+				return
+			}
+			l.counters.spilled.Add(1)
+		}
+	}
+
+	// refill moves spilled messages back into the buffer as it drains.
+	refill := func() {
+		for len(buf) < l.size && spill.pending > 0 {
+			msg, err := spill.read()
+			if err != nil {
+				l.logger.Warn("dropped spilled messages",
+					"count", spill.pending, "err", err)
+				l.counters.dropped.Add(uint64(spill.pending))
+				spill.close()
+				return
+			}
+			buf = append(buf, msg)
+		}
+	}
+
+	go func() {
+		defer close(done)
+		defer spill.close()
+		defer func() {
+			l.lost = len(buf) + spill.pending
+		}()
+
+		flushing, flushed := l.flushing, false
+		for {
+			if flushed && len(buf) == 0 && spill.pending == 0 {
+				return
+			}
+
+			var (
+				out  chan<- *Message
+				next *Message
+			)
+			if len(buf) > 0 {
+				out, next = l.out, buf[0]
+			}
+
+			select {
+			case <-stop:
+				return
+			case <-flushing:
+				flushing, flushed = nil, true
+			case msg := <-l.in:
+				push(msg)
+			case out <- next:
+				buf[0] = nil
+				buf = buf[1:]
+				refill()
+			}
+		}
+	}()
+
+	return done
+}
+
+// spillFile is a first in, first out queue of messages on disk.  The file is
+// removed whenever the queue empties.
+type spillFile struct {
+	dir     string
+	file    *os.File
+	reader  *os.File
+	w       *bufio.Writer
+	enc     *json.Encoder
+	dec     *json.Decoder
+	pending int
+}
+
+func (s *spillFile) write(msg *Message) error {
+	if s.file == nil {
+		f, err := os.CreateTemp(s.dir, "supervise-spill-*.jsonl")
+		if err != nil {
+			return err
+		}
+
+		r, err := os.Open(f.Name())
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+			return err
+		}
+
+		s.file, s.reader = f, r
+		s.w = bufio.NewWriter(f)
+		s.enc = json.NewEncoder(s.w)
+		s.dec = json.NewDecoder(r)
+	}
+
+	if err := s.enc.Encode(msg); err != nil {
+		return err
+	}
+
+	s.pending++
+	return nil
+}
+
+func (s *spillFile) read() (*Message, error) {
+	if err := s.w.Flush(); err != nil {
+		return nil, err
+	}
+
+	msg := new(Message)
+	if err := s.dec.Decode(msg); err != nil {
+		return nil, err
+	}
+
+	s.pending--
+	if s.pending == 0 {
+		s.close()
+	}
+
+	return msg, nil
+}
+
+func (s *spillFile) close() {
+	if s.file == nil {
+		return
+	}
+
+	s.file.Close()
+	s.reader.Close()
+	os.Remove(s.file.Name())
+	*s = spillFile{dir: s.dir}
+}
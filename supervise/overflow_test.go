@@ -0,0 +1,74 @@
+package supervise_test
+
+import (
+	"os"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/mstreet3/go-blogs/supervise"
+	"github.com/mstreet3/go-blogs/supervise/faults"
+)
+
+func TestOverflowPolicies(t *testing.T) {
+	tests := []struct {
+		policy  supervise.OverflowPolicy
+		want    []string
+		dropped uint64
+		spilled uint64
+	}{
+		{supervise.DropNewest, []string{"1", "2", "3", "4", "5"}, 5, 0},
+		{supervise.DropOldest, []string{"6", "7", "8", "9", "10"}, 5, 0},
+		{supervise.SpillToDisk, []string{"1", "2", "3", "4", "5", "6", "7", "8", "9", "10"}, 0, 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy.String(), func(t *testing.T) {
+			// Ten messages are read before the consumer receives any.
+			var steps []faults.Step
+			for i := 1; i <= 10; i++ {
+				steps = append(steps, faults.OK(strconv.Itoa(i)))
+			}
+			conn := faults.NewReader(1, faults.Script(append(steps, faults.Hung())...))
+			dir := t.TempDir()
+			counters := new(supervise.OverflowCounters)
+			stop := make(chan struct{})
+			out := make(chan *supervise.Message)
+
+			done, _ := supervise.ReaderWard(stop, conn, time.Millisecond,
+				supervise.WithMessages(out), supervise.WithOverflow(tt.policy, 5),
+				supervise.WithOverflowCounters(counters), supervise.WithSpillDir(dir))
+			eventually(t, "every message to be read", func() bool { return conn.Reads() > 10 })
+
+			var got []string
+			for len(got) < len(tt.want) {
+				got = append(got, (<-out).Content)
+			}
+
+			close(stop)
+			conn.Close()
+			for stopped := false; !stopped; {
+				select {
+				case m := <-out:
+					t.Errorf("received %q after every message kept", m.Content)
+				case <-done:
+					stopped = true
+				}
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("received %q, want %q", got, tt.want)
+			}
+			if got := counters.Dropped(); got != tt.dropped {
+				t.Errorf("dropped %d messages, want %d", got, tt.dropped)
+			}
+			if got := counters.Spilled(); got != tt.spilled {
+				t.Errorf("spilled %d messages, want %d", got, tt.spilled)
+			}
+			if files, _ := os.ReadDir(dir); len(files) != 0 {
+				t.Errorf("left %d spill files behind", len(files))
+			}
+		})
+	}
+}
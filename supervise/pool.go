@@ -0,0 +1,191 @@
+package supervise
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// ConnectionPool keeps size stewards reading from network until stop is
+// closed.  See ConnectionPoolContext.
+func ConnectionPool(
+	stop <-chan struct{}, network Connector, pulseInterval time.Duration,
+	size int, resize <-chan int, opts ...Option,
+) (<-chan struct{}, <-chan error) {
+	ctx, release := stopContext(stop)
+	done, errs := ConnectionPoolContext(ctx, network, pulseInterval, size,
+		resize, opts...)
+	releaseWhenDone(done, release)
+	return done, errs
+}
+
+// ConnectionPoolContext keeps size connections to network, each read by a
+// ward of its own steward, until ctx is done.  Every steward heals its own
+// connection and all of them send their messages to the channel given by
+// WithMessages.  Receiving a new size on resize starts or stops stewards,
+// newest first, until size are running.  Closing resize keeps the pool at its
+// latest size.
+//
+// The stewards are named after the pool, as in "name/1", and their errors are
+// forwarded as *ChildError values.  A steward that gives up makes the pool
+// stop every steward and give up too.  WithStatus and WithControl do not
+// apply to a pool, nor does WithHealthPolicy, whose policy its stewards would
+// share; WithHealthPolicyFunc gives each a policy of its own.
+func ConnectionPoolContext(
+	ctx context.Context, network Connector, pulseInterval time.Duration,
+	size int, resize <-chan int, opts ...Option,
+) (<-chan struct{}, <-chan error) {
+
+	o := newOptions(opts)
+
+	stop := ctx.Done()
+	done := make(chan struct{})
+	errs := make(chan error, 1)
+
+	logger := o.log("pool")
+
+	dropErr := func(e error) {
+		o.dropErr("pool", e)
+	}
+
+	sendErr := func(e error) {
+		select {
+		case <-stop:
+			return
+		case errs <- e:
+		default:
+			dropErr(e)
+		}
+	}
+
+	// member is a steward of the pool.
+	type member struct {
+		name     string
+		cancel   context.CancelCauseFunc
+		stopping bool
+	}
+
+	var (
+		members []*member
+		running int
+		started int
+	)
+	exits := make(chan *member)
+
+	// start starts a steward along with a watcher that forwards its errors
+	// and reports once it is done.
+	start := func() {
+		started++
+		m := &member{name: fmt.Sprintf("%s/%d", o.name, started)}
+		memberCtx, cancel := context.WithCancelCause(ctx)
+		m.cancel = cancel
+		members = append(members, m)
+		running++
+
+		memberOpts := append(opts[:len(opts):len(opts)],
+			WithName(m.name), WithStatus(nil), WithControl(nil),
+			WithHealthPolicyFunc(o.newHealth))
+
+		logger.Info("starting steward", "steward", m.name)
+		memberDone, memberErrs := ConnectionStewardContext(memberCtx,
+			network, pulseInterval, memberOpts...)
+
+		go func() {
+			for e := range memberErrs {
+				sendErr(&ChildError{Name: m.name, Err: e})
+			}
+			<-memberDone
+			exits <- m
+		}()
+	}
+
+	// stopNewest stops the newest steward that is still running.
+	stopNewest := func() {
+		for i := len(members) - 1; i >= 0; i-- {
+			if m := members[i]; !m.stopping {
+				logger.Info("stopping steward", "steward", m.name)
+				m.stopping = true
+				m.cancel(errStopped)
+				running--
+				return
+			}
+		}
+	}
+
+	// remove forgets a steward that is done.
+	remove := func(m *member) {
+		for i := range members {
+			if members[i] == m {
+				members = append(members[:i], members[i+1:]...)
+				return
+			}
+		}
+	}
+
+	// resizeTo starts or stops stewards until n are running.
+	resizeTo := func(n int) {
+		if n < 0 {
+			n = 0
+		}
+		if n != running {
+			logger.Info("resizing pool", "from", running, "to", n)
+		}
+		for running < n {
+			start()
+		}
+		for running > n {
+			stopNewest()
+		}
+	}
+
+	cleanup := func() {
+		for _, m := range members {
+			m.cancel(errStopped)
+		}
+		for len(members) > 0 {
+			remove(<-exits)
+		}
+
+		sendCause(ctx, errs, dropErr)
+		close(errs)
+		close(done)
+	}
+
+	go func() {
+		defer cleanup()
+
+		resizeTo(size)
+
+		for {
+			select {
+			case <-stop:
+				return
+			case n, ok := <-resize:
+				if !ok {
+					// The size is final once resize is
+					// closed.
+					resize = nil
+					continue
+				}
+				resizeTo(n)
+			case m := <-exits:
+				remove(m)
+
+				if m.stopping {
+					continue
+				}
+				running--
+
+				// A steward stops on its own only once it gives
+				// up, having sent why.
+				if ctx.Err() == nil {
+					logger.Error("giving up; steward gave up",
+						"steward", m.name)
+					return
+				}
+			}
+		}
+	}()
+
+	return done, errs
+}
@@ -0,0 +1,240 @@
+package supervise_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mstreet3/go-blogs/supervise"
+	"github.com/mstreet3/go-blogs/supervise/faults"
+)
+
+func TestConnectionPoolResizesNewestFirst(t *testing.T) {
+	var buf syncBuffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	network := faults.NewNetwork(1)
+	stop := make(chan struct{})
+	resize := make(chan int)
+
+	done, _ := supervise.ConnectionPool(stop, network, time.Millisecond, 2,
+		resize, supervise.WithName("pool"), supervise.WithLogger(logger))
+	eventually(t, "2 connections", func() bool { return network.Attempts() == 2 })
+
+	resize <- 4
+	eventually(t, "4 connections", func() bool { return network.Attempts() == 4 })
+	resize <- 1
+	eventually(t, "3 connections to close", func() bool { return network.Closes() == 3 })
+	resize <- -1
+	eventually(t, "every connection to close", func() bool { return network.Closes() == 4 })
+
+	close(stop)
+	<-done
+
+	if got := network.Attempts(); got != 4 {
+		t.Errorf("connected %d times, want 4", got)
+	}
+
+	var stopped []string
+	dec := json.NewDecoder(bytes.NewReader(buf.Bytes()))
+	for dec.More() {
+		var record struct{ Msg, Steward string }
+		if err := dec.Decode(&record); err != nil {
+			t.Fatal(err)
+		}
+		if record.Msg == "stopping steward" {
+			stopped = append(stopped, record.Steward)
+		}
+	}
+	if want := []string{"pool/4", "pool/3", "pool/2", "pool/1"}; !slices.Equal(stopped, want) {
+		t.Errorf("stopped %q, want %q", stopped, want)
+	}
+}
+
+func TestConnectionPoolGivesUpWithItsStewards(t *testing.T) {
+	network := faults.NewNetwork(1,
+		faults.Script(faults.Fail(supervise.ErrFatalSocketError)))
+
+	done, errs := supervise.ConnectionPool(make(chan struct{}), network,
+		time.Millisecond, 3, nil, supervise.WithName("pool"),
+		supervise.WithRestartIntensity(2, time.Hour))
+
+	var last error
+	for err := range errs {
+		last = err
+	}
+	<-done
+
+	var ce *supervise.ChildError
+	if !errors.As(last, &ce) || !strings.HasPrefix(ce.Name, "pool/") {
+		t.Fatalf("last error is %v, want a *ChildError from the pool", last)
+	}
+	var ri *supervise.RestartIntensityError
+	if !errors.As(last, &ri) || ri.MaxRestarts != 2 {
+		t.Errorf("last error is %v, want a *RestartIntensityError", last)
+	}
+	if a, c := network.Attempts(), network.Closes(); a != c {
+		t.Errorf("connected %d times but closed %d connections", a, c)
+	}
+}
+
+func TestConnectionPoolKeepsSizeOnceResizeIsClosed(t *testing.T) {
+	network := faults.NewNetwork(1)
+	stop := make(chan struct{})
+	resize := make(chan int)
+
+	done, _ := supervise.ConnectionPool(stop, network, 5*time.Millisecond, 3,
+		resize)
+
+	eventually(t, "3 connections", func() bool { return network.Attempts() == 3 })
+
+	resize <- 2
+	close(resize)
+	eventually(t, "a connection to close", func() bool { return network.Closes() == 1 })
+
+	close(stop)
+	<-done
+
+	if got := network.Attempts(); got != 3 {
+		t.Errorf("connected %d times after closing resize, want 3", got)
+	}
+	if got := network.Closes(); got != 3 {
+		t.Errorf("closed %d connections after stopping, want 3", got)
+	}
+}
+
+func TestConnectionPoolGivesEveryStewardItsOwnHealthPolicy(t *testing.T) {
+	network := faults.NewNetwork(1,
+		faults.Errors(supervise.ErrFatalSocketError, 0.5))
+	stop := make(chan struct{})
+
+	var mu sync.Mutex
+	policies := 0
+	newPolicy := func() supervise.HealthPolicy {
+		mu.Lock()
+		defer mu.Unlock()
+		policies++
+		return supervise.ConsecutiveErrors(2)
+	}
+
+	done, errs := supervise.ConnectionPool(stop, network, time.Millisecond, 3,
+		nil, supervise.WithHealthPolicyFunc(newPolicy))
+	go func() {
+		for range errs {
+		}
+	}()
+
+	// Every steward judges its reads at the same time, which the race
+	// detector catches if their policies are shared.  Each restart shows
+	// that a policy judged a fatal error.
+	eventually(t, "every steward to restart", func() bool {
+		return network.Attempts() >= 9
+	})
+	close(stop)
+	<-done
+
+	mu.Lock()
+	defer mu.Unlock()
+	if policies != 3 {
+		t.Errorf("made %d health policies, want 3", policies)
+	}
+}
+
+func TestConnectionPoolIgnoresASharedHealthPolicy(t *testing.T) {
+	network := faults.NewNetwork(1,
+		faults.Script(faults.Fail(supervise.ErrFatalSocketError)))
+	stop := make(chan struct{})
+
+	var judged atomic.Int32
+	shared := supervise.HealthFunc(func(error) bool {
+		judged.Add(1)
+		return false
+	})
+
+	done, _ := supervise.ConnectionPool(stop, network, time.Millisecond, 3,
+		nil, supervise.WithHealthPolicy(shared))
+
+	// The default policy restarts every steward on its fatal error.
+	eventually(t, "every steward to restart", func() bool {
+		return network.Attempts() >= 6
+	})
+	close(stop)
+	<-done
+
+	if n := judged.Load(); n != 0 {
+		t.Errorf("the shared policy judged %d errors, want 0", n)
+	}
+}
+
+// numberedNetwork opens connections that read their own number, counting
+// from 1.  The first fail connections break after their first message.
+type numberedNetwork struct {
+	mu   sync.Mutex
+	n    int
+	fail int
+}
+
+func (n *numberedNetwork) Connect() (supervise.Conn, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.n++
+	return &numberedConn{id: strconv.Itoa(n.n), breaks: n.n <= n.fail}, nil
+}
+
+type numberedConn struct {
+	id     string
+	breaks bool
+	read   bool
+}
+
+func (c *numberedConn) Read() (*supervise.Message, error) {
+	if c.read && c.breaks {
+		return nil, supervise.ErrFatalSocketError
+	}
+	c.read = true
+	return &supervise.Message{Content: c.id}, nil
+}
+
+func (c *numberedConn) Close() error { return nil }
+
+func TestConnectionPoolMergesTheMessagesOfEverySteward(t *testing.T) {
+	network := &numberedNetwork{fail: 2}
+	stop := make(chan struct{})
+	resize := make(chan int)
+	out := make(chan *supervise.Message)
+
+	done, _ := supervise.ConnectionPool(stop, network, time.Millisecond, 2,
+		resize, supervise.WithMessages(out))
+
+	seen := make(map[string]bool)
+	await := func(ids ...string) {
+		t.Helper()
+		deadline := time.After(time.Second)
+		for _, id := range ids {
+			for !seen[id] {
+				select {
+				case msg := <-out:
+					seen[msg.Content] = true
+				case <-deadline:
+					t.Fatalf("no message from connection %s", id)
+				}
+			}
+		}
+	}
+
+	// Both stewards restart on new connections after the first ones
+	// break, and a steward started by resizing joins them on out.
+	await("1", "2", "3", "4")
+	resize <- 3
+	await("5")
+
+	close(stop)
+	<-done
+}
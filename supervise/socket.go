@@ -0,0 +1,200 @@
+package supervise
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"time"
+)
+
+// ErrFrameTooLarge is returned by a Framing that reads a frame longer than its
+// limit.
+var ErrFrameTooLarge = errors.New("supervise: frame too large")
+
+// defaultMaxFrame limits frames when a Framing is given no limit.
+const defaultMaxFrame = 1 << 20
+
+// Framing reads the next frame of a stream.
+type Framing func(r *bufio.Reader) ([]byte, error)
+
+// LineFraming reads newline-delimited frames of at most maxLen bytes, without
+// the trailing "\n" or "\r\n".  A maxLen of zero allows frames of up to 1 MiB.
+func LineFraming(maxLen int) Framing {
+	if maxLen <= 0 {
+		maxLen = defaultMaxFrame
+	}
+
+	return func(r *bufio.Reader) ([]byte, error) {
+		var frame []byte
+		for {
+			chunk, err := r.ReadSlice('\n')
+			frame = append(frame, chunk...)
+
+			switch {
+			case err == nil:
+				frame = bytes.TrimSuffix(frame[:len(frame)-1], []byte("\r"))
+				if len(frame) > maxLen {
+					return nil, ErrFrameTooLarge
+				}
+				return frame, nil
+			case errors.Is(err, bufio.ErrBufferFull):
+				// Allow for a trailing "\r" still to come.
+				if len(frame) > maxLen+1 {
+					return nil, ErrFrameTooLarge
+				}
+			case errors.Is(err, io.EOF) && len(frame) > 0:
+				return nil, io.ErrUnexpectedEOF
+			default:
+				return nil, err
+			}
+		}
+	}
+}
+
+// LengthPrefixFraming reads frames that each follow their length as a 4-byte
+// big-endian unsigned integer, refusing frames longer than maxLen bytes.  A
+// maxLen of zero allows frames of up to 1 MiB.
+func LengthPrefixFraming(maxLen int) Framing {
+	if maxLen <= 0 {
+		maxLen = defaultMaxFrame
+	}
+
+	return func(r *bufio.Reader) ([]byte, error) {
+		var prefix [4]byte
+		if _, err := io.ReadFull(r, prefix[:]); err != nil {
+			return nil, err
+		}
+
+		n := binary.BigEndian.Uint32(prefix[:])
+		if uint64(n) > uint64(maxLen) {
+			return nil, fmt.Errorf("%w: %d bytes", ErrFrameTooLarge, n)
+		}
+
+		frame := make([]byte, n)
+		if _, err := io.ReadFull(r, frame); err != nil {
+			if errors.Is(err, io.EOF) {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+
+		return frame, nil
+	}
+}
+
+// SocketConnector dials stream sockets, such as TCP or Unix sockets, and
+// splits what it reads from each connection into messages by its Framing.
+// Every failed read is an ErrFatalSocketError, since the stream can no longer
+// be trusted to be at the start of a frame.
+type SocketConnector struct {
+	// Network and Address are passed to Dialer.DialContext.
+	Network string
+	Address string
+
+	// Framing splits the stream into messages, by lines if nil.
+	Framing Framing
+
+	// Dialer dials the connections.
+	Dialer net.Dialer
+}
+
+// TCP returns a SocketConnector for the TCP socket at address.
+func TCP(address string, framing Framing) *SocketConnector {
+	return &SocketConnector{Network: "tcp", Address: address, Framing: framing}
+}
+
+// Unix returns a SocketConnector for the Unix stream socket at path.
+func Unix(path string, framing Framing) *SocketConnector {
+	return &SocketConnector{Network: "unix", Address: path, Framing: framing}
+}
+
+// Connect dials a new connection.
+func (s *SocketConnector) Connect() (Conn, error) {
+	return s.ConnectContext(context.Background())
+}
+
+// ConnectContext dials a new connection, giving up once ctx is done.
+func (s *SocketConnector) ConnectContext(ctx context.Context) (Conn, error) {
+	conn, err := s.Dialer.DialContext(ctx, s.Network, s.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	framing := s.Framing
+	if framing == nil {
+		framing = LineFraming(0)
+	}
+
+	return &socketConn{
+		conn:    conn,
+		r:       bufio.NewReader(conn),
+		framing: framing,
+	}, nil
+}
+
+// socketConn reads framed messages from a stream socket.
+type socketConn struct {
+	conn    net.Conn
+	r       *bufio.Reader
+	framing Framing
+	err     error
+}
+
+// Read reads the next message without a deadline.
+func (c *socketConn) Read() (*Message, error) {
+	return c.ReadContext(context.Background())
+}
+
+// ReadContext reads the next message, giving up at the deadline of ctx or
+// once ctx is done.  A read given up on may have stopped in the middle of a
+// frame, so every later read fails.
+func (c *socketConn) ReadContext(ctx context.Context) (*Message, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+
+	deadline, hasDeadline := ctx.Deadline()
+	if err := c.conn.SetReadDeadline(deadline); err != nil {
+		return nil, c.fail(err)
+	}
+
+	// Interrupt the read once ctx is done by moving the deadline into the
+	// past.
+	stop := context.AfterFunc(ctx, func() {
+		c.conn.SetReadDeadline(time.Unix(1, 0))
+	})
+	defer stop()
+
+	frame, err := c.framing(c.r)
+	if err != nil {
+		// The socket may time out at the deadline of ctx just before
+		// ctx itself is done.
+		if hasDeadline && errors.Is(err, os.ErrDeadlineExceeded) {
+			<-ctx.Done()
+		}
+		if ctx.Err() != nil {
+			c.fail(err)
+			return nil, context.Cause(ctx)
+		}
+		return nil, c.fail(err)
+	}
+
+	return &Message{Content: string(frame)}, nil
+}
+
+// fail records err as the fatal error of the connection and returns it.
+func (c *socketConn) fail(err error) error {
+	c.err = fmt.Errorf("%w: %w", ErrFatalSocketError, err)
+	return c.err
+}
+
+// Close closes the socket, interrupting any read in progress.
+func (c *socketConn) Close() error {
+	return c.conn.Close()
+}
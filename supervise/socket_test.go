@@ -0,0 +1,214 @@
+package supervise_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mstreet3/go-blogs/supervise"
+)
+
+// frames reads every frame of in with framing through a buffer of size bytes
+// and returns them along with the error that ended them.
+func frames(framing supervise.Framing, in string, size int) ([]string, error) {
+	r := bufio.NewReaderSize(strings.NewReader(in), size)
+
+	var got []string
+	for {
+		frame, err := framing(r)
+		if err != nil {
+			return got, err
+		}
+		got = append(got, string(frame))
+	}
+}
+
+func TestLineFraming(t *testing.T) {
+	tests := []struct {
+		name   string
+		maxLen int
+		in     string
+		want   []string
+		err    error
+	}{
+		{"lines", 0, "a\nbc\n", []string{"a", "bc"}, io.EOF},
+		{"crlf", 0, "a\r\nbc\r\n\r\n", []string{"a", "bc", ""}, io.EOF},
+		{"only a trailing cr is trimmed", 0, "a\rb\n", []string{"a\rb"}, io.EOF},
+		{"longer than the buffer", 32, strings.Repeat("x", 30) + "\n", []string{strings.Repeat("x", 30)}, io.EOF},
+		{"crlf across the buffer", 16, strings.Repeat("x", 16) + "\r\n", []string{strings.Repeat("x", 16)}, io.EOF},
+		{"too long", 20, strings.Repeat("x", 40) + "\n", nil, supervise.ErrFrameTooLarge},
+		{"too long within the buffer", 4, "a\nxxxxx\n", []string{"a"}, supervise.ErrFrameTooLarge},
+		{"truncated", 0, "a\nbc", []string{"a"}, io.ErrUnexpectedEOF},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := frames(supervise.LineFraming(tt.maxLen), tt.in, 16)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("read %q, want %q", got, tt.want)
+			}
+			if !errors.Is(err, tt.err) {
+				t.Errorf("ended with %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+// prefixed returns frames each prefixed with its length.
+func prefixed(frames ...string) string {
+	var b strings.Builder
+	for _, f := range frames {
+		binary.Write(&b, binary.BigEndian, uint32(len(f)))
+		b.WriteString(f)
+	}
+	return b.String()
+}
+
+func TestLengthPrefixFraming(t *testing.T) {
+	tests := []struct {
+		name   string
+		maxLen int
+		in     string
+		want   []string
+		err    error
+	}{
+		{"frames", 0, prefixed("a", "", "line\nbreak"), []string{"a", "", "line\nbreak"}, io.EOF},
+		{"longer than the buffer", 0, prefixed(strings.Repeat("x", 40)), []string{strings.Repeat("x", 40)}, io.EOF},
+		{"too long", 4, prefixed("abcd", "abcde"), []string{"abcd"}, supervise.ErrFrameTooLarge},
+		{"truncated frame", 0, prefixed("abc")[:6], nil, io.ErrUnexpectedEOF},
+		{"truncated prefix", 0, prefixed("abc")[:2], nil, io.ErrUnexpectedEOF},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := frames(supervise.LengthPrefixFraming(tt.maxLen), tt.in, 16)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("read %q, want %q", got, tt.want)
+			}
+			if !errors.Is(err, tt.err) {
+				t.Errorf("ended with %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+// listen accepts connections on l until it is closed and hands each to serve.
+func listen(t *testing.T, l net.Listener, serve func(c net.Conn)) {
+	t.Cleanup(func() { l.Close() })
+
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer c.Close()
+				serve(c)
+			}()
+		}
+	}()
+}
+
+func TestSocketConnectorReadsFramesOverTCP(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	listen(t, l, func(c net.Conn) {
+		io.WriteString(c, "a\r\nb\n")
+	})
+
+	conn, err := supervise.TCP(l.Addr().String(), nil).Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	for _, want := range []string{"a", "b"} {
+		msg, err := conn.Read()
+		if err != nil || msg.Content != want {
+			t.Fatalf("read %v, %v, want %q", msg, err, want)
+		}
+	}
+
+	// The server hanging up is fatal, and stays so.
+	for i := 0; i < 2; i++ {
+		if _, err := conn.Read(); !errors.Is(err, supervise.ErrFatalSocketError) || !errors.Is(err, io.EOF) {
+			t.Errorf("read after hang-up returned %v, want a fatal %v", err, io.EOF)
+		}
+	}
+}
+
+func TestSocketConnectorReadsFramesOverUnix(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "feed.sock")
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	listen(t, l, func(c net.Conn) {
+		io.WriteString(c, prefixed("a", "b"))
+		time.Sleep(time.Second)
+	})
+
+	stop := make(chan struct{})
+	out := make(chan *supervise.Message)
+	done, _ := supervise.ConnectionSteward(stop,
+		supervise.Unix(path, supervise.LengthPrefixFraming(0)), time.Millisecond,
+		supervise.WithMessages(out))
+
+	var got []string
+	for len(got) < 2 {
+		got = append(got, (<-out).Content)
+	}
+	close(stop)
+	<-done
+
+	if want := []string{"a", "b"}; !slices.Equal(got, want) {
+		t.Errorf("read %q, want %q", got, want)
+	}
+}
+
+func TestSocketConnectorPoisonsAnInterruptedRead(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrote := make(chan struct{})
+	listen(t, l, func(c net.Conn) {
+		// Half a frame, which the interrupted read consumes, and then a
+		// whole one.
+		io.WriteString(c, "hal")
+		time.Sleep(30 * time.Millisecond)
+		io.WriteString(c, "f\nwhole\n")
+		close(wrote)
+		time.Sleep(time.Second)
+	})
+
+	conn, err := supervise.TCP(l.Addr().String(), nil).Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	r := conn.(supervise.ContextReader)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := r.ReadContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("interrupted read returned %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	<-wrote
+	if msg, err := r.ReadContext(context.Background()); !errors.Is(err, supervise.ErrFatalSocketError) {
+		t.Errorf("read after an interrupted read returned %v, %v, want %v",
+			msg, err, supervise.ErrFatalSocketError)
+	}
+}
@@ -0,0 +1,245 @@
+package supervise
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// StatusError is returned by an SSEConnector whose server responded with
+// anything but 200 OK.  A 5xx response is an ErrFatalSocketError.  Since it
+// is a failure to connect, a StatusError never reaches a steward's health
+// policy: the steward retries it after its backoff, counts it towards its
+// restart intensity and, given WithFailover, towards failing over.
+type StatusError struct {
+	Code   int
+	Status string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("sse: unexpected response %s", e.Status)
+}
+
+// Is reports whether a 5xx response matches ErrFatalSocketError.
+func (e *StatusError) Is(target error) bool {
+	return target == ErrFatalSocketError && e.Code >= 500
+}
+
+// SSEConnector opens server-sent event streams over HTTP.  Each event read
+// becomes a Message whose Content is the event's data and whose ID and Type
+// are the last event ID and the event type.  A stream that breaks or ends is
+// an ErrFatalSocketError, and every new stream resumes from the last event ID
+// seen by any earlier one.  An SSEConnector is safe for concurrent use.
+type SSEConnector struct {
+	// URL is the address of the stream.
+	URL string
+
+	// Client sends the requests, http.DefaultClient if nil.
+	Client *http.Client
+
+	// Header is added to every request.
+	Header http.Header
+
+	mu     sync.Mutex
+	lastID string
+}
+
+// SSE returns an SSEConnector for the stream at url.
+func SSE(url string) *SSEConnector {
+	return &SSEConnector{URL: url}
+}
+
+// LastEventID returns the ID sent with the next request.
+func (s *SSEConnector) LastEventID() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastID
+}
+
+func (s *SSEConnector) setLastEventID(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastID = id
+}
+
+// Connect opens a new stream.
+func (s *SSEConnector) Connect() (Conn, error) {
+	return s.ConnectContext(context.Background())
+}
+
+// ConnectContext opens a new stream that lasts until it is closed or ctx is
+// done.
+func (s *SSEConnector) ConnectContext(ctx context.Context) (Conn, error) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.URL, nil)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	for key, values := range s.Header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Cache-Control", "no-cache")
+
+	lastID := s.LastEventID()
+	if lastID != "" {
+		req.Header.Set("Last-Event-ID", lastID)
+	}
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("%w: %w", ErrFatalSocketError, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		cancel()
+		return nil, &StatusError{Code: resp.StatusCode, Status: resp.Status}
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != "text/event-stream" {
+		resp.Body.Close()
+		cancel()
+		return nil, fmt.Errorf("sse: unexpected content type %q", mediaType)
+	}
+
+	return &sseConn{
+		connector: s,
+		body:      resp.Body,
+		r:         bufio.NewReader(resp.Body),
+		cancel:    cancel,
+		lastID:    lastID,
+	}, nil
+}
+
+// sseConn reads events from a server-sent event stream.
+type sseConn struct {
+	connector *SSEConnector
+	body      io.ReadCloser
+	r         *bufio.Reader
+	cancel    context.CancelFunc
+	lastID    string
+	err       error
+
+	// pending receives the event being parsed by a read that gave up.
+	pending chan readResult
+}
+
+// Read reads the next event without a deadline.
+func (c *sseConn) Read() (*Message, error) {
+	return c.ReadContext(context.Background())
+}
+
+// ReadContext reads the next event, giving up once ctx is done.  Giving up
+// leaves the stream open: the event being parsed is returned by the next
+// read, so that a slow stream survives a read timeout.
+func (c *sseConn) ReadContext(ctx context.Context) (*Message, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+
+	if c.pending == nil {
+		pending := make(chan readResult, 1)
+		c.pending = pending
+
+		go func() {
+			msg, err := c.next()
+			pending <- readResult{msg: msg, err: err}
+		}()
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil, context.Cause(ctx)
+	case r := <-c.pending:
+		c.pending = nil
+		if r.err != nil {
+			c.err = fmt.Errorf("%w: %w", ErrFatalSocketError, r.err)
+			return nil, c.err
+		}
+		return r.msg, nil
+	}
+}
+
+// next parses lines until an event is dispatched, following the event stream
+// interpretation of the HTML standard.
+func (c *sseConn) next() (*Message, error) {
+	var (
+		data      strings.Builder
+		eventType string
+	)
+
+	for {
+		line, err := c.r.ReadString('\n')
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+
+		// A blank line dispatches the event, if it has any data, and
+		// records its ID for the next stream to resume from.
+		if line == "" {
+			c.connector.setLastEventID(c.lastID)
+
+			if data.Len() == 0 {
+				eventType = ""
+				continue
+			}
+
+			if eventType == "" {
+				eventType = "message"
+			}
+
+			return &Message{
+				Content: strings.TrimSuffix(data.String(), "\n"),
+				ID:      c.lastID,
+				Type:    eventType,
+			}, nil
+		}
+
+		// Lines starting with a colon are comments, such as keep-alives.
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+
+		switch field {
+		case "data":
+			data.WriteString(value)
+			data.WriteByte('\n')
+		case "event":
+			eventType = value
+		case "id":
+			if !strings.ContainsRune(value, 0) {
+				c.lastID = value
+			}
+		}
+	}
+}
+
+// Close ends the stream.
+func (c *sseConn) Close() error {
+	c.cancel()
+	return c.body.Close()
+}
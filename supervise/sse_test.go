@@ -0,0 +1,190 @@
+package supervise_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mstreet3/go-blogs/supervise"
+)
+
+// stream serves body as an event stream and flushes it to the client.
+func stream(body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream; charset=utf-8")
+		io.WriteString(w, body)
+		w.(http.Flusher).Flush()
+	}
+}
+
+func TestSSEParsesEvents(t *testing.T) {
+	srv := httptest.NewServer(stream(": keep-alive\n\n" +
+		"data: first\n\n" +
+		"id: 7\r\nevent: tick\r\ndata: line one\r\ndata:line two\r\n\r\n" +
+		"event: ignored\nid: 8\n\n" +
+		"data\n\n" +
+		"id: 9\nunknown: field\ndata: last\n\n" +
+		"data: unfinished\n"))
+	defer srv.Close()
+
+	connector := supervise.SSE(srv.URL)
+	conn, err := connector.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	want := []supervise.Message{
+		{Content: "first", Type: "message"},
+		{Content: "line one\nline two", ID: "7", Type: "tick"},
+		{Content: "", ID: "8", Type: "message"},
+		{Content: "last", ID: "9", Type: "message"},
+	}
+	for i, w := range want {
+		msg, err := conn.Read()
+		if err != nil {
+			t.Fatalf("event %d: %v", i, err)
+		}
+		if *msg != w {
+			t.Errorf("event %d is %+v, want %+v", i, *msg, w)
+		}
+	}
+
+	// The stream ended in the middle of an event.
+	_, err = conn.Read()
+	if !errors.Is(err, supervise.ErrFatalSocketError) || !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("read past the end returned %v, want a fatal %v", err, io.ErrUnexpectedEOF)
+	}
+	if got := connector.LastEventID(); got != "9" {
+		t.Errorf("LastEventID() = %q, want %q", got, "9")
+	}
+}
+
+func TestSSEResumesFromTheLastEventID(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		lastIDs []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		lastIDs = append(lastIDs, r.Header.Get("Last-Event-ID"))
+		first := len(lastIDs) == 1
+		mu.Unlock()
+
+		if r.Header.Get("Accept") != "text/event-stream" {
+			http.Error(w, "not acceptable", http.StatusNotAcceptable)
+			return
+		}
+
+		if first {
+			stream("id: 1\ndata: a\n\nid: 2\ndata: b\n\n")(w, r)
+			return
+		}
+		stream("id: 3\ndata: c\n\n")(w, r)
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+
+	stop := make(chan struct{})
+	out := make(chan *supervise.Message)
+	done, _ := supervise.ConnectionSteward(stop, supervise.SSE(srv.URL),
+		time.Millisecond, supervise.WithMessages(out))
+
+	var got []string
+	for len(got) < 3 {
+		got = append(got, (<-out).Content)
+	}
+	close(stop)
+	<-done
+
+	if want := []string{"a", "b", "c"}; !slices.Equal(got, want) {
+		t.Errorf("read %q, want %q", got, want)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if want := []string{"", "2"}; !slices.Equal(lastIDs, want) {
+		t.Errorf("sent Last-Event-ID %q, want %q", lastIDs, want)
+	}
+}
+
+func TestSSEKeepsTheStreamOpenWhenAReadGivesUp(t *testing.T) {
+	next := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		stream("data: a\n\n")(w, r)
+		<-next
+		stream("data: b\n\n")(w, r)
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+	defer close(next)
+
+	conn, err := supervise.SSE(srv.URL).Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	reader := conn.(supervise.ContextReader)
+
+	if m, err := reader.ReadContext(context.Background()); err != nil || m.Content != "a" {
+		t.Fatalf("first read returned %v, %v, want %q", m, err, "a")
+	}
+
+	// The second event is only sent once the read waiting for it gave up.
+	ctx, cancel := context.WithCancelCause(context.Background())
+	cancel(supervise.ErrReadTimeout)
+	if _, err := reader.ReadContext(ctx); !errors.Is(err, supervise.ErrReadTimeout) {
+		t.Fatalf("read with a done context returned %v, want %v", err, supervise.ErrReadTimeout)
+	}
+
+	next <- struct{}{}
+	if m, err := reader.ReadContext(context.Background()); err != nil || m.Content != "b" {
+		t.Errorf("read after giving up returned %v, %v, want %q", m, err, "b")
+	}
+}
+
+func TestSSERefusesBadResponses(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		code    int
+		fatal   bool
+	}{
+		{"server error", func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "down", http.StatusServiceUnavailable)
+		}, http.StatusServiceUnavailable, true},
+		{"client error", func(w http.ResponseWriter, r *http.Request) {
+			http.NotFound(w, r)
+		}, http.StatusNotFound, false},
+		{"not a stream", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			io.WriteString(w, "{}")
+		}, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(tt.handler)
+			defer srv.Close()
+
+			_, err := supervise.SSE(srv.URL).Connect()
+			if err == nil {
+				t.Fatal("connected")
+			}
+
+			var se *supervise.StatusError
+			if tt.code != 0 && (!errors.As(err, &se) || se.Code != tt.code) {
+				t.Errorf("got %v, want a *StatusError for %d", err, tt.code)
+			}
+			if got := errors.Is(err, supervise.ErrFatalSocketError); got != tt.fatal {
+				t.Errorf("errors.Is(%v, ErrFatalSocketError) = %v, want %v", err, got, tt.fatal)
+			}
+		})
+	}
+}
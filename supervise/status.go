@@ -0,0 +1,187 @@
+package supervise
+
+import (
+	"sync"
+	"time"
+)
+
+// State is the state of a steward.
+type State int
+
+const (
+	// StateIdle is the state of a steward that has not started yet.
+	StateIdle State = iota
+
+	// StateConnecting is the state of a steward attempting to connect.
+	StateConnecting
+
+	// StateBackingOff is the state of a steward waiting to connect again
+	// after a failed attempt.
+	StateBackingOff
+
+	// StateRunning is the state of a steward whose ward is reading.
+	StateRunning
+
+	// StateRestarting is the state of a steward replacing an unhealthy
+	// ward.
+	StateRestarting
+
+	// StateStopped is the state of a steward that is done.
+	StateStopped
+
+	// StatePaused is the state of a steward paused by a Control.
+	StatePaused
+)
+
+func (s State) String() string {
+	switch s {
+	case StateIdle:
+		return "idle"
+	case StateConnecting:
+		return "connecting"
+	case StateBackingOff:
+		return "backing off"
+	case StateRunning:
+		return "running"
+	case StateRestarting:
+		return "restarting"
+	case StateStopped:
+		return "stopped"
+	case StatePaused:
+		return "paused"
+	default:
+		return "unknown"
+	}
+}
+
+// Restart describes the restart of an unhealthy ward.
+type Restart struct {
+	// At is when the ward was found unhealthy.
+	At time.Time
+
+	// Generation is the generation of the unhealthy ward.
+	Generation int
+
+	// Cause is why the ward was unhealthy.
+	Cause error
+
+	// Uptime is how long the ward ran before it was found unhealthy.
+	Uptime time.Duration
+
+	// Downtime is how long it took for the next ward to start, or zero
+	// while none has.
+	Downtime time.Duration
+}
+
+// Status is a snapshot of a steward.
+type Status struct {
+	// State is the state of the steward.
+	State State
+
+	// Attempt and Generation are those of the latest event.
+	Attempt    int
+	Generation int
+
+	// Endpoint is the index of the endpoint in use.  See Event.Endpoint.
+	Endpoint int
+
+	// ConnectedSince is when the current connection was made, or zero
+	// without a connection.
+	ConnectedSince time.Time
+
+	// LastError is the error of the latest event that had one, which may
+	// be a failure to connect, the cause of a restart or a *CloseError.
+	LastError error
+
+	// Restarts are the latest restarts, oldest first.
+	Restarts []Restart
+}
+
+// StatusTracker keeps the status of a steward and the history of its latest
+// restarts.  It is safe for concurrent use, but must be given to only one
+// steward.
+type StatusTracker struct {
+	history int
+
+	mu       sync.Mutex
+	status   Status
+	started  time.Time
+	restarts []Restart
+	healing  bool
+}
+
+// NewStatusTracker returns a StatusTracker of an idle steward that remembers
+// its last history restarts.
+func NewStatusTracker(history int) *StatusTracker {
+	if history < 0 {
+		history = 0
+	}
+
+	return &StatusTracker{history: history}
+}
+
+// Status returns a snapshot of the steward.
+func (t *StatusTracker) Status() Status {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s := t.status
+	s.Restarts = append([]Restart(nil), t.restarts...)
+	return s
+}
+
+// event records a step in the lifecycle of a steward.
+func (t *StatusTracker) event(e Event) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s := &t.status
+	s.Attempt, s.Generation, s.Endpoint = e.Attempt, e.Generation, e.Endpoint
+	if e.Err != nil {
+		s.LastError = e.Err
+	}
+
+	switch e.Kind {
+	case Connecting:
+		s.State = StateConnecting
+	case ConnectFailed:
+		s.State = StateBackingOff
+	case Connected:
+		s.ConnectedSince = e.At
+	case WardStarted:
+		s.State = StateRunning
+		t.started = e.At
+
+		// The ward heals the latest restart.
+		if n := len(t.restarts); t.healing && n > 0 {
+			t.restarts[n-1].Downtime = e.At.Sub(t.restarts[n-1].At)
+		}
+		t.healing = false
+	case WardUnhealthy:
+		s.State = StateRestarting
+
+		if t.history == 0 {
+			break
+		}
+		if len(t.restarts) == t.history {
+			t.restarts = append(t.restarts[:0], t.restarts[1:]...)
+		}
+		t.healing = true
+		t.restarts = append(t.restarts, Restart{
+			At:         e.At,
+			Generation: e.Generation,
+			Cause:      e.Err,
+			Uptime:     e.At.Sub(t.started),
+		})
+	case ConnectionClosed:
+		s.ConnectedSince = time.Time{}
+	case StewardStopped:
+		s.State = StateStopped
+	case Paused:
+		s.State = StatePaused
+	}
+}
@@ -0,0 +1,156 @@
+package supervise_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mstreet3/go-blogs/supervise"
+	"github.com/mstreet3/go-blogs/supervise/faults"
+)
+
+func TestStatusTrackerFollowsTheSteward(t *testing.T) {
+	clock := supervise.NewFakeClock(epoch)
+	network := faults.NewNetwork(1,
+		faults.Script(faults.Fail(supervise.ErrFatalSocketError)))
+	tracker := supervise.NewStatusTracker(2)
+	stop := make(chan struct{})
+
+	if s := tracker.Status(); s.State != supervise.StateIdle {
+		t.Errorf("state before starting is %v, want %v", s.State, supervise.StateIdle)
+	}
+
+	// Every ward reads on the first tick, a second after it starts, and
+	// fails; every restart waits a minute.
+	backingOff := make(chan struct{}, 1)
+	backoff := supervise.BackoffFunc(func(int, time.Duration) time.Duration {
+		backingOff <- struct{}{}
+		return time.Minute
+	})
+	done, _ := supervise.ConnectionSteward(stop, network, 2*time.Second,
+		supervise.WithClock(clock), supervise.WithStatus(tracker),
+		supervise.WithBackoff(backoff))
+
+	// Snapshots are taken while the steward runs.
+	polled := make(chan struct{})
+	go func() {
+		defer close(polled)
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				tracker.Status()
+			}
+		}
+	}()
+
+	for gen := 1; gen <= 3; gen++ {
+		eventually(t, "a running ward", func() bool {
+			s := tracker.Status()
+			return s.State == supervise.StateRunning && s.Generation == gen
+		})
+		s := tracker.Status()
+		if want := clock.Now(); !s.ConnectedSince.Equal(want) {
+			t.Errorf("generation %d connected since %v, want %v", gen, s.ConnectedSince, want)
+		}
+
+		// Once the backoff is asked for its delay, the steward's only
+		// timer is the one about to wait it out.
+		clock.Advance(time.Second)
+		<-backingOff
+		clock.BlockUntil(1)
+		clock.Advance(time.Minute)
+	}
+	eventually(t, "a fourth ward", func() bool {
+		return tracker.Status().Generation == 4
+	})
+
+	s := tracker.Status()
+	if s.State != supervise.StateRunning {
+		t.Errorf("state is %v, want %v", s.State, supervise.StateRunning)
+	}
+	if !errors.Is(s.LastError, supervise.ErrFatalSocketError) {
+		t.Errorf("last error is %v, want %v", s.LastError, supervise.ErrFatalSocketError)
+	}
+
+	// Only the last two restarts are kept.
+	if len(s.Restarts) != 2 {
+		t.Fatalf("kept %d restarts, want 2", len(s.Restarts))
+	}
+	for i, r := range s.Restarts {
+		gen := i + 2
+		at := epoch.Add(time.Duration(gen-1)*time.Minute + time.Duration(gen)*time.Second)
+		if r.Generation != gen || !r.At.Equal(at) {
+			t.Errorf("restart %d is of generation %d at %v, want %d at %v",
+				i, r.Generation, r.At.Sub(epoch), gen, at.Sub(epoch))
+		}
+		if !errors.Is(r.Cause, supervise.ErrFatalSocketError) {
+			t.Errorf("restart %d was caused by %v, want %v", i, r.Cause, supervise.ErrFatalSocketError)
+		}
+		if r.Uptime != time.Second || r.Downtime != time.Minute {
+			t.Errorf("restart %d was up %v and down %v, want %v and %v",
+				i, r.Uptime, r.Downtime, time.Second, time.Minute)
+		}
+	}
+
+	close(stop)
+	<-done
+	<-polled
+
+	s = tracker.Status()
+	if s.State != supervise.StateStopped || !s.ConnectedSince.IsZero() {
+		t.Errorf("stopped steward is %v, connected since %v", s.State, s.ConnectedSince)
+	}
+}
+
+func TestStatusTrackerReturnsCopiesOfItsHistory(t *testing.T) {
+	clock := supervise.NewFakeClock(epoch)
+	network := faults.NewNetwork(1,
+		faults.Script(faults.Fail(supervise.ErrFatalSocketError)))
+	tracker := supervise.NewStatusTracker(1)
+	stop := make(chan struct{})
+
+	done, _ := supervise.ConnectionSteward(stop, network, 2*time.Second,
+		supervise.WithClock(clock), supervise.WithStatus(tracker),
+		supervise.WithBackoff(supervise.ConstantBackoff(time.Minute)))
+
+	eventually(t, "a running ward", func() bool {
+		return tracker.Status().State == supervise.StateRunning
+	})
+	clock.Advance(time.Second)
+	eventually(t, "a restart", func() bool {
+		return len(tracker.Status().Restarts) == 1
+	})
+
+	s := tracker.Status()
+	s.Restarts[0].Generation = 100
+	if got := tracker.Status().Restarts[0].Generation; got != 1 {
+		t.Errorf("restart is of generation %d after changing a snapshot, want 1", got)
+	}
+
+	close(stop)
+	<-done
+}
+
+func TestStateString(t *testing.T) {
+	tests := []struct {
+		state supervise.State
+		want  string
+	}{
+		{supervise.StateIdle, "idle"},
+		{supervise.StateConnecting, "connecting"},
+		{supervise.StateBackingOff, "backing off"},
+		{supervise.StateRunning, "running"},
+		{supervise.StateRestarting, "restarting"},
+		{supervise.StateStopped, "stopped"},
+		{supervise.StatePaused, "paused"},
+		{supervise.State(-1), "unknown"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.state.String(); got != tt.want {
+			t.Errorf("State(%d).String() = %q, want %q", tt.state, got, tt.want)
+		}
+	}
+}
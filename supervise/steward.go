@@ -0,0 +1,590 @@
+package supervise
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// ErrCloseTimeout is the cause of a CloseError for a connection whose Close
+// did not return within the timeout given by WithCloseTimeout.
+var ErrCloseTimeout = errors.New("supervise: close timed out")
+
+// CloseError is sent by a steward that failed to close a connection.
+type CloseError struct {
+	Err error
+}
+
+func (e *CloseError) Error() string {
+	return fmt.Sprintf("closing connection: %v", e.Err)
+}
+
+func (e *CloseError) Unwrap() error {
+	return e.Err
+}
+
+// ConnectionSteward heals wards reading from network until stop is closed.
+// See ConnectionStewardContext.
+func ConnectionSteward(
+	stop <-chan struct{}, network Connector, pulseInterval time.Duration,
+	opts ...Option,
+) (<-chan struct{}, <-chan error) {
+	ctx, release := stopContext(stop)
+	done, errs := ConnectionStewardContext(ctx, network, pulseInterval, opts...)
+	releaseWhenDone(done, release)
+	return done, errs
+}
+
+// ConnectionStewardContext connects to network, starts a ward on the
+// connection and restarts the ward on a new connection whenever it becomes
+// unhealthy until ctx is done.  Failures to close a connection are sent
+// as *CloseError values.
+func ConnectionStewardContext(
+	ctx context.Context, network Connector, pulseInterval time.Duration,
+	opts ...Option,
+) (<-chan struct{}, <-chan error) {
+
+	o := newOptions(opts)
+
+	// Define channels that other clients may consume.
+	done := make(chan struct{})
+	errs := make(chan error, 1)
+
+	// health contains the business logic for when to trigger a ward
+	// restart.
+	health := o.healthPolicy()
+	if health == nil {
+		health = ForClass(ErrFatalSocketError, ConsecutiveErrors(1))
+	}
+
+	// The steward keeps the state that outlives each ward.
+	s := newSteward(ctx, network, pulseInterval, health, errs, o)
+
+	go func() {
+		// Cleanup and close the owned channels.
+		defer func() {
+			s.cleanup()
+			close(errs)
+			close(done)
+		}()
+
+		for {
+			select {
+			case <-s.stop:
+				return
+			default:
+				// Attempt to connect to the network.
+				conn, err := s.connect()
+				if err != nil {
+					// Retry after the backoff unless the
+					// steward gives up.
+					if s.connectFailed(err) {
+						return
+					}
+					continue
+				}
+
+				// Start a new ward to read from the connection.
+				w := s.newWard()
+				reading, readerErrs := readerWard(w.ctx, Abandonable(conn),
+					pulseInterval/2, &w.opts)
+				s.emit(WardStarted, nil)
+
+				// Monitor the ward's health.
+				restart := monitor(w.ctx, readerErrs, w.heartbeat,
+					s.stallTimeout, s.health, &w.opts)
+
+				// Wait for the signal to restart or to stop
+				// completely, obeying any commands meanwhile.
+				var exit *wardExit
+				for exit == nil {
+					select {
+					case <-s.stop:
+						exit = s.stopping(w, reading)
+					case cause, ok := <-restart:
+						// The monitor finishes without a
+						// cause only once it is stopped
+						// along with the steward.
+						if !ok {
+							exit = s.stopping(w, reading)
+							break
+						}
+						exit = s.unhealthy(w, cause)
+					case cmd := <-s.commands:
+						exit = s.obey(w, cmd)
+					case <-w.probeTicks:
+						s.probe(w)
+					case p := <-w.probed:
+						exit = s.failBack(w, p)
+					}
+				}
+				s.stopProbes(w)
+
+				// Cleanup the ward and connection.
+				w.stop(errStopped)
+				if errors.Is(exit.cause, ErrStalled) || errors.Is(exit.cause, ErrReadTimeout) {
+					// A stalled or timed-out ward may be stuck in
+					// a read that only closing the connection can
+					// interrupt.
+					s.closeConn(conn)
+					<-reading
+					s.emit(WardStopped, nil)
+				} else {
+					<-reading
+					s.emit(WardStopped, nil)
+					s.closeConn(conn)
+				}
+
+				// Wait for the monitor to finish with the policy.
+				for range restart {
+				}
+
+				// Move on as the ward's exit demands.
+				if !s.heal(w, exit) {
+					return
+				}
+			}
+		}
+	}()
+
+	return done, errs
+}
+
+// steward holds the state of a ConnectionSteward that outlives its wards.  It
+// is only used by the steward's goroutine.
+type steward struct {
+	o        *options
+	ctx      context.Context
+	cancel   context.CancelCauseFunc
+	stop     <-chan struct{}
+	commands <-chan command
+	errs     chan error
+	logger   *slog.Logger
+
+	// msgs is the outlet shared by every ward, delivering until
+	// delivering is closed.
+	msgs       *outlet
+	delivering <-chan struct{}
+	stopOutlet context.CancelFunc
+
+	health       HealthPolicy
+	wardOpts     options
+	stallTimeout time.Duration
+	closeTimeout time.Duration
+	retries      *backoffState
+	resetAfter   time.Duration
+	intensity    *restartIntensity
+
+	// endpoints are network followed by its fallbacks, endpoint indexes
+	// the one in use and handoff is a connection to it made by a
+	// fail-back probe.
+	endpoints       []Connector
+	endpoint        int
+	handoff         Conn
+	failAfter       int
+	connectFailures int
+
+	// attempt and generation count connections and wards for events.
+	attempt    int
+	generation int
+	terminal   error
+
+	// drainStart is when the steward began to drain, and inFlight records
+	// that a draining ward had to be force-stopped.
+	drainStart time.Time
+	inFlight   bool
+}
+
+// wardRun is a ward started by a steward.
+type wardRun struct {
+	ctx      context.Context
+	stop     context.CancelCauseFunc
+	opts     options
+	logger   *slog.Logger
+	started  time.Time
+	draining chan struct{}
+
+	// heartbeat carries the ward's heartbeats to its monitor alone, so
+	// that a beat left over from an earlier ward never reaches it.
+	heartbeat chan time.Time
+
+	// probeTicks paces fail-back probes, whose results arrive on probed.
+	// cancelProbe cancels the probe in flight while probing.
+	probeTicks  <-chan time.Time
+	stopTicks   func()
+	probed      chan probeResult
+	probing     bool
+	cancelProbe context.CancelFunc
+}
+
+// wardExit is why a steward stopped a ward.
+type wardExit struct {
+	// cause is why the ward was unhealthy, if it was.
+	cause error
+
+	// paused is the command that paused the steward, if one did.
+	paused *command
+
+	// failback is the connection made by a fail-back probe, if one was.
+	failback *probeResult
+}
+
+func newSteward(
+	ctx context.Context, network Connector, pulseInterval time.Duration,
+	health HealthPolicy, errs chan error, o *options,
+) *steward {
+	// A Control may stop the steward as if ctx were done.
+	ctx, cancel := context.WithCancelCause(ctx)
+
+	s := &steward{
+		o:         o,
+		ctx:       ctx,
+		cancel:    cancel,
+		stop:      ctx.Done(),
+		commands:  o.control.listen(),
+		errs:      errs,
+		logger:    o.log("steward"),
+		health:    health,
+		endpoints: append([]Connector{network}, o.fallbacks...),
+		failAfter: o.failAfter,
+		intensity: &restartIntensity{max: o.maxRestarts, window: o.window},
+	}
+
+	// Every ward delivers its messages through the steward's outlet so that
+	// buffered messages survive a restart.  A draining outlet outlives the
+	// shutdown signal until it is flushed or the drain deadline passes.
+	outletBase := ctx
+	if o.drain > 0 {
+		outletBase = context.WithoutCancel(ctx)
+	}
+	outletCtx, stopOutlet := context.WithCancel(outletBase)
+	s.stopOutlet = stopOutlet
+	s.delivering = closedSignal
+	if o.messages != nil {
+		s.msgs = newOutlet(o)
+		s.delivering = s.msgs.run(outletCtx)
+	}
+
+	if o.readTimeout > 0 {
+		s.health = AnyOf(ForClass(ErrReadTimeout, ConsecutiveErrors(1)), health)
+	}
+
+	// Failed connections are retried every pulseInterval unless a backoff
+	// was given, which then also delays the restart of unhealthy wards.
+	s.retries = &backoffState{backoff: o.backoff}
+	if o.backoff == nil {
+		s.retries.backoff = ConstantBackoff(pulseInterval)
+	}
+
+	s.resetAfter = o.backoffReset
+	if s.resetAfter == 0 {
+		s.resetAfter = 10 * pulseInterval
+	}
+
+	s.wardOpts = *o
+	s.wardOpts.outlet = s.msgs
+
+	if o.stallPulses > 0 {
+		s.stallTimeout = time.Duration(o.stallPulses) * pulseInterval
+	}
+
+	s.closeTimeout = o.closeTimeout
+	if s.closeTimeout == 0 {
+		s.closeTimeout = 10 * pulseInterval
+	}
+
+	if s.failAfter < 1 {
+		s.failAfter = 1
+	}
+
+	return s
+}
+
+// emit records an event in the metrics and the status and sends it to the
+// listener, if any, waiting for it to be received unless the steward is
+// stopped.
+func (s *steward) emit(kind EventKind, err error) {
+	e := Event{
+		Kind:       kind,
+		At:         s.o.clock.Now(),
+		Attempt:    s.attempt,
+		Generation: s.generation,
+		Endpoint:   s.endpoint,
+		Err:        err,
+	}
+
+	s.o.metrics.event(s.o.name, e, s.o.clock)
+	s.o.status.event(e)
+
+	if s.o.events == nil {
+		return
+	}
+
+	// A stopped steward still sends the events its listener is ready for.
+	select {
+	case s.o.events <- e:
+		return
+	default:
+	}
+
+	select {
+	case s.o.events <- e:
+	case <-s.stop:
+		s.logger.Warn("dropped event; listener not ready", "event", e)
+		if s.o.eventCounters != nil {
+			s.o.eventCounters.dropped.Add(1)
+		}
+	}
+}
+
+func (s *steward) dropErr(e error) {
+	s.o.dropErr("steward", e)
+}
+
+// sendErr sends e without blocking unless the steward is stopped.
+func (s *steward) sendErr(e error) {
+	select {
+	case <-s.stop:
+		return
+	case s.errs <- e:
+	default:
+		s.dropErr(e)
+	}
+}
+
+// connect connects to the current endpoint.
+func (s *steward) connect() (Conn, error) {
+	s.attempt++
+	s.emit(Connecting, nil)
+
+	conn, err := s.dial()
+	if err != nil {
+		return nil, err
+	}
+
+	s.connectFailures = 0
+	s.emit(Connected, nil)
+	return conn, nil
+}
+
+// connectFailed reports a failure to connect and waits for the backoff.  It
+// reports true if the steward gave up instead.
+func (s *steward) connectFailed(err error) bool {
+	s.logger.Warn("failed to connect", "attempt", s.attempt, "err", err)
+	s.emit(ConnectFailed, err)
+	s.sendErr(err)
+
+	if s.giveUp(err) {
+		return true
+	}
+
+	// An endpoint that keeps failing is left for the next one.
+	if s.connectFailures++; s.connectFailures >= s.failAfter {
+		s.failover(err)
+	}
+
+	s.wait()
+	return false
+}
+
+// newWard prepares a ward to start on the latest connection.
+func (s *steward) newWard() *wardRun {
+	s.generation++
+
+	// The ward and its monitor log the attempt and generation that started
+	// them.
+	w := &wardRun{
+		opts:      s.wardOpts,
+		started:   s.o.clock.Now(),
+		draining:  make(chan struct{}),
+		heartbeat: make(chan time.Time, 1),
+		stopTicks: func() {},
+		probed:    make(chan probeResult, 1),
+	}
+	w.opts.logger = s.o.logger.With("attempt", s.attempt,
+		"generation", s.generation)
+	w.opts.draining = w.draining
+
+	// The ward sends heartbeats to its monitor, which observes successful
+	// reads and, when enabled, detects stalls.
+	w.opts.heartbeat = w.heartbeat
+	w.logger = w.opts.log("steward")
+	w.logger.Info("starting ward")
+
+	// A draining ward outlives the shutdown signal until it finishes or
+	// the drain deadline passes.  Reads that never return are abandoned so
+	// that stopping the ward never waits on them.
+	base := s.ctx
+	if s.o.drain > 0 {
+		base = context.WithoutCancel(s.ctx)
+	}
+	w.ctx, w.stop = context.WithCancelCause(base)
+
+	// A steward that failed over probes the endpoints it prefers to fail
+	// back to them.
+	if s.o.probe > 0 && s.endpoint > 0 {
+		ticker := s.o.clock.NewTicker(s.o.probe)
+		w.probeTicks, w.stopTicks = ticker.C(), ticker.Stop
+	}
+
+	return w
+}
+
+// stopping stops w once the steward is stopped.
+func (s *steward) stopping(w *wardRun, reading <-chan struct{}) *wardExit {
+	w.logger.Info("received shutdown signal; stopping ward")
+	s.drainWard(w, reading)
+	return &wardExit{}
+}
+
+// unhealthy stops w once its monitor finds it unhealthy.
+func (s *steward) unhealthy(w *wardRun, cause error) *wardExit {
+	w.logger.Warn("stopping unhealthy ward", "err", cause)
+	s.emit(WardUnhealthy, cause)
+	return &wardExit{cause: cause}
+}
+
+// heal moves on after a ward has stopped and its connection is closed, and
+// reports false once the steward gives up.
+func (s *steward) heal(w *wardRun, exit *wardExit) bool {
+	// A ward that stayed healthy long enough resets the backoff before the
+	// restart is delayed.
+	if s.o.clock.Now().Sub(w.started) >= s.resetAfter {
+		s.retries.reset()
+	}
+
+	switch {
+	case exit.paused != nil:
+		s.pause(*exit.paused)
+		return true
+	case exit.failback != nil:
+		// Failing back moves straight on to the probe's connection.
+		s.endpoint, s.handoff = exit.failback.endpoint, exit.failback.conn
+		s.emit(FailedBack, nil)
+		return true
+	case errors.Is(exit.cause, ErrRestartRequested):
+		// Restarts on request neither count towards the restart
+		// intensity nor wait for the backoff.
+		return true
+	case exit.cause != nil:
+		if s.giveUp(exit.cause) {
+			return false
+		}
+
+		// An unhealthy ward moves the steward on to the next endpoint.
+		s.failover(exit.cause)
+	}
+
+	if s.o.backoff != nil {
+		s.wait()
+	}
+	return true
+}
+
+// closeConn closes conn and reports a failure to close on both the error and
+// event streams.  A close that outlasts the close timeout is reported and
+// abandoned rather than waited on.
+func (s *steward) closeConn(conn Conn) {
+	closed := make(chan error, 1)
+	go func() {
+		closed <- conn.Close()
+	}()
+
+	timer := s.o.clock.NewTimer(s.closeTimeout)
+	defer timer.Stop()
+
+	var err error
+	select {
+	case err = <-closed:
+	case <-timer.C():
+		err = fmt.Errorf("%w after %v", ErrCloseTimeout, s.closeTimeout)
+	}
+
+	if err != nil {
+		err = &CloseError{Err: err}
+		s.logger.Warn("failed to close connection", "err", err)
+
+		// Close errors are reported even while shutting down.
+		select {
+		case s.errs <- err:
+		default:
+			s.dropErr(err)
+		}
+	}
+
+	s.emit(ConnectionClosed, err)
+}
+
+// giveUp reports whether the restart caused by err exhausted the steward's
+// restart intensity, in which case the terminal error is sent.
+func (s *steward) giveUp(err error) bool {
+	s.terminal = s.intensity.record(s.o.clock.Now(), err)
+	if s.terminal != nil {
+		s.logger.Error("giving up", "err", s.terminal)
+		sendLast(s.errs, s.terminal, s.dropErr)
+		return true
+	}
+	return false
+}
+
+// wait blocks for the next backoff delay, or until a command cuts it short,
+// and reports false if the steward was stopped in the meantime.
+func (s *steward) wait() bool {
+	timer := s.o.clock.NewTimer(s.retries.next())
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return false
+		case <-timer.C():
+			return true
+		case cmd := <-s.commands:
+			switch cmd.kind {
+			case pauseCommand:
+				s.pause(cmd)
+				return !isClosed(s.stop)
+			case restartCommand:
+				cmd.reply <- nil
+				return true
+			default:
+				s.obeyAlways(cmd)
+			}
+		}
+	}
+}
+
+// cleanup drains the steward, if it should, and reports how it stopped.
+func (s *steward) cleanup() {
+	// A connection handed over by a probe but never read is closed.
+	if s.handoff != nil {
+		s.closeConn(s.handoff)
+	}
+
+	if err := s.closeOutlet(); err != nil {
+		s.logger.Warn("drain deadline passed", "err", err)
+
+		select {
+		case s.errs <- err:
+		default:
+			s.dropErr(err)
+		}
+
+		if s.terminal == nil {
+			s.terminal = err
+		}
+	}
+
+	if s.terminal == nil {
+		if cause := context.Cause(s.ctx); !errors.Is(cause, errStopped) {
+			s.terminal = cause
+		}
+	}
+	s.emit(StewardStopped, s.terminal)
+
+	sendCause(s.ctx, s.errs, s.dropErr)
+	s.cancel(errStopped)
+	s.o.control.close()
+}
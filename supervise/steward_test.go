@@ -0,0 +1,188 @@
+package supervise_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mstreet3/go-blogs/supervise"
+	"github.com/mstreet3/go-blogs/supervise/faults"
+)
+
+// eventually fails t unless cond holds within a second.
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestConnectionStewardReconnectsAfterAFatalError(t *testing.T) {
+	// Every connection reads one message and then breaks.
+	network := faults.NewNetwork(1, faults.Script(faults.OK("a"),
+		faults.Fail(supervise.ErrFatalSocketError), faults.Hung()))
+	stop := make(chan struct{})
+	out := make(chan *supervise.Message)
+
+	done, errs := supervise.ConnectionSteward(stop, network, time.Millisecond,
+		supervise.WithMessages(out))
+	go func() {
+		for range errs {
+		}
+	}()
+
+	for i := 0; i < 3; i++ {
+		if m := <-out; m.Content != "a" {
+			t.Fatalf("read %q, want %q", m.Content, "a")
+		}
+	}
+	eventually(t, "a third connection", func() bool { return network.Attempts() >= 3 })
+
+	close(stop)
+	<-done
+
+	if opened, closed := network.Attempts(), network.Closes(); opened != closed {
+		t.Errorf("closed %d of %d connections", closed, opened)
+	}
+}
+
+func TestConnectionStewardStopsWithoutReportingAnError(t *testing.T) {
+	network := faults.NewNetwork(1)
+	stop := make(chan struct{})
+
+	done, errs := supervise.ConnectionSteward(stop, network, time.Millisecond)
+	eventually(t, "a connection", func() bool { return network.Attempts() == 1 })
+	close(stop)
+
+	for err := range errs {
+		t.Errorf("got error %v", err)
+	}
+	<-done
+
+	if got := network.Closes(); got != 1 {
+		t.Errorf("closed %d connections, want 1", got)
+	}
+}
+
+func TestConnectionStewardRestartsAStalledWard(t *testing.T) {
+	// The first read of every connection hangs until it is closed.
+	network := faults.NewNetwork(1, faults.Script(faults.Hung()))
+	stop := make(chan struct{})
+	events := make(chan supervise.Event, 100)
+
+	done, _ := supervise.ConnectionSteward(stop, network, time.Millisecond,
+		supervise.WithStallDetection(3), supervise.WithEvents(events))
+
+	var cause error
+	for e := range events {
+		if e.Kind == supervise.WardUnhealthy {
+			cause = e.Err
+			break
+		}
+	}
+	eventually(t, "a second connection", func() bool { return network.Attempts() >= 2 })
+
+	close(stop)
+	go func() {
+		for range events {
+		}
+	}()
+	<-done
+
+	if !errors.Is(cause, supervise.ErrStalled) {
+		t.Errorf("ward was unhealthy because of %v, want %v", cause, supervise.ErrStalled)
+	}
+	if opened, closed := network.Attempts(), network.Closes(); opened != closed {
+		t.Errorf("closed %d of %d connections", closed, opened)
+	}
+}
+
+func TestConnectionStewardDeliversMessagesAcrossRestarts(t *testing.T) {
+	network := faults.NewNetwork(1, faults.Script(faults.OK("1"), faults.OK("2"),
+		faults.Fail(supervise.ErrFatalSocketError), faults.Hung()))
+	stop := make(chan struct{})
+	out := make(chan *supervise.Message)
+
+	done, errs := supervise.ConnectionSteward(stop, network, time.Millisecond,
+		supervise.WithMessages(out))
+	go func() {
+		for range errs {
+		}
+	}()
+
+	var got []string
+	for len(got) < 6 {
+		got = append(got, (<-out).Content)
+	}
+	close(stop)
+	<-done
+
+	if want := []string{"1", "2", "1", "2", "1", "2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("read %q, want %q", got, want)
+	}
+
+	select {
+	case _, ok := <-out:
+		if !ok {
+			t.Error("steward closed its messages channel")
+		}
+	default:
+	}
+}
+
+func TestConnectionStewardReportsCloseErrors(t *testing.T) {
+	errClose := errors.New("close")
+
+	tests := []struct {
+		name string
+		opts []faults.Option
+		want error
+	}{
+		{"failed", []faults.Option{faults.CloseErrors(errClose, 1)}, errClose},
+		{"timed out", []faults.Option{faults.CloseLatency(time.Second)}, supervise.ErrCloseTimeout},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			network := faults.NewNetwork(1, tt.opts...)
+			stop := make(chan struct{})
+			events := make(chan supervise.Event, 100)
+
+			done, errs := supervise.ConnectionSteward(stop, network,
+				time.Millisecond, supervise.WithEvents(events),
+				supervise.WithCloseTimeout(10*time.Millisecond))
+			eventually(t, "a connection", func() bool { return network.Attempts() == 1 })
+			close(stop)
+
+			var sent []error
+			for err := range errs {
+				sent = append(sent, err)
+			}
+			<-done
+			close(events)
+
+			var ce *supervise.CloseError
+			if len(sent) != 1 || !errors.As(sent[0], &ce) || !errors.Is(ce, tt.want) {
+				t.Errorf("sent %v, want a *CloseError of %v", sent, tt.want)
+			}
+
+			closed := false
+			for e := range events {
+				if e.Kind == supervise.ConnectionClosed {
+					closed = true
+					if !errors.As(e.Err, &ce) || !errors.Is(ce, tt.want) {
+						t.Errorf("connection closed with %v, want a *CloseError of %v", e.Err, tt.want)
+					}
+				}
+			}
+			if !closed {
+				t.Error("no connection closed event")
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package supervise
+
+import (
+	"context"
+	"time"
+)
+
+// ReadMode decides how a ward paces its reads.
+type ReadMode int
+
+const (
+	// Poll reads once on every tick, which caps a ward at one message per
+	// tick.
+	Poll ReadMode = iota
+
+	// Stream reads back to back as fast as the Reader delivers, for
+	// sources that push their messages.  Ticks only pace the heartbeats,
+	// and, after a failed read, the next read waits for a tick so that a
+	// Reader stuck failing does not spin.
+	Stream
+)
+
+func (m ReadMode) String() string {
+	switch m {
+	case Poll:
+		return "poll"
+	case Stream:
+		return "stream"
+	default:
+		return "unknown"
+	}
+}
+
+// streamReads reads from conn in a goroutine of its own, handing every
+// outcome to handle, and calls pulse on every tick until handle reports false
+// or ctx is done.  A draining ward hands the outcome of the read in flight to
+// handle before returning.  It returns once the reading goroutine has
+// finished, which requires conn to honour ctx.
+func streamReads(
+	ctx context.Context, conn Reader, o *options, ticks <-chan time.Time,
+	handle func(*Message, error) bool, pulse func(),
+) {
+	stop := ctx.Done()
+	results := make(chan readResult)
+	resume := make(chan struct{})
+	reading := make(chan struct{})
+
+	go func() {
+		defer close(reading)
+
+		for {
+			// A draining ward starts no new reads.
+			if isClosed(o.draining) {
+				return
+			}
+
+			msg, err := readWithin(ctx, conn, o.readTimeout, o.clock)
+
+			select {
+			case <-stop:
+				return
+			case results <- readResult{msg: msg, err: err}:
+			}
+
+			if err == nil {
+				continue
+			}
+
+			select {
+			case <-stop:
+				return
+			case <-o.draining:
+				return
+			case <-resume:
+			}
+		}
+	}()
+
+	defer func() { <-reading }()
+
+	failed := false
+	for {
+		select {
+		case <-stop:
+			return
+		case <-reading:
+			return
+		case <-ticks:
+			pulse()
+
+			if failed {
+				failed = false
+				select {
+				case <-stop:
+					return
+				case resume <- struct{}{}:
+				}
+			}
+		case r := <-results:
+			if !handle(r.msg, r.err) {
+				return
+			}
+			failed = r.err != nil
+		}
+	}
+}
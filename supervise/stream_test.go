@@ -0,0 +1,128 @@
+package supervise_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mstreet3/go-blogs/supervise"
+	"github.com/mstreet3/go-blogs/supervise/faults"
+)
+
+func TestStreamingWardReadsWithoutWaitingForTicks(t *testing.T) {
+	clock := supervise.NewFakeClock(epoch)
+	conn := faults.NewReader(1)
+	stop := make(chan struct{})
+	out := make(chan *supervise.Message)
+	beats := make(chan time.Time, 1)
+
+	done, _ := supervise.ReaderWard(stop, conn, time.Second,
+		supervise.WithClock(clock), supervise.WithMessages(out),
+		supervise.WithHeartbeat(beats), supervise.WithReadMode(supervise.Stream))
+
+	// The clock never ticks, so a polling ward would read nothing.
+	for i := 0; i < 100; i++ {
+		select {
+		case <-out:
+		case <-time.After(time.Second):
+			t.Fatalf("read %d messages, want 100", i)
+		}
+	}
+	if fired(beats) {
+		t.Error("sent a heartbeat without a tick")
+	}
+
+	// A ward waiting on its consumer sends no heartbeats.
+	go func() {
+		for {
+			select {
+			case <-out:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	clock.Advance(time.Second)
+	select {
+	case <-beats:
+	case <-time.After(time.Second):
+		t.Error("sent no heartbeat on a tick")
+	}
+
+	close(stop)
+	<-done
+}
+
+func TestStreamingWardWaitsForATickAfterAFailedRead(t *testing.T) {
+	errBroken := errors.New("broken")
+	clock := supervise.NewFakeClock(epoch)
+	conn := faults.NewReader(1, faults.Script(faults.Fail(errBroken),
+		faults.OK("a")))
+	stop := make(chan struct{})
+	out := make(chan *supervise.Message)
+
+	done, errs := supervise.ReaderWard(stop, conn, time.Second,
+		supervise.WithClock(clock), supervise.WithMessages(out),
+		supervise.WithReadMode(supervise.Stream))
+
+	if err := <-errs; err != errBroken {
+		t.Fatalf("got error %v, want %v", err, errBroken)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	if got := conn.Reads(); got != 1 {
+		t.Fatalf("read %d times before a tick, want 1", got)
+	}
+
+	clock.Advance(time.Second)
+	select {
+	case m := <-out:
+		if m.Content != "a" {
+			t.Errorf("read %q, want %q", m.Content, "a")
+		}
+	case <-time.After(time.Second):
+		t.Error("did not read again after a tick")
+	}
+
+	close(stop)
+	<-done
+}
+
+func TestStreamingWardAbandonsAHungReadWhenStopped(t *testing.T) {
+	conn := faults.NewReader(1, faults.Script(faults.Hung()))
+	defer conn.Close()
+	stop := make(chan struct{})
+
+	done, errs := supervise.ReaderWard(stop, conn, time.Millisecond,
+		supervise.WithReadMode(supervise.Stream))
+
+	eventually(t, "a read", func() bool { return conn.Reads() == 1 })
+	close(stop)
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("ward did not stop")
+	}
+	for err := range errs {
+		t.Errorf("got error %v", err)
+	}
+}
+
+func TestReadModeString(t *testing.T) {
+	tests := []struct {
+		mode supervise.ReadMode
+		want string
+	}{
+		{supervise.Poll, "poll"},
+		{supervise.Stream, "stream"},
+		{supervise.ReadMode(-1), "unknown"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.mode.String(); got != tt.want {
+			t.Errorf("ReadMode(%d).String() = %q, want %q", tt.mode, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+// Package supervise provides the ward, monitor and steward workers from the
+// "Healing Unhealthy Goroutines" article.
+//
+// A ward consumes a Reader and forwards any errors it reads, a monitor
+// consumes those errors and signals when the ward is unhealthy and a steward
+// connects to the network, starts a ward, monitors it and restarts it on a
+// fresh connection whenever the monitor signals.
+//
+// A Supervisor generalises the steward to an ordered list of child workers,
+// any of which may be a steward or another Supervisor, and a ConnectionPool
+// keeps a resizable number of stewards reading from the same network.
+package supervise
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrFatalSocketError is returned by a Reader that cannot recover without a
+// new connection.
+var ErrFatalSocketError = errors.New("fatal socket error")
+
+// Connector opens connections that can be read from.
+type Connector interface {
+	Connect() (Conn, error)
+}
+
+// Conn is a connection that can be read from.  Each connection owns its
+// Close, which releases any Read still blocked on it.
+type Conn interface {
+	Reader
+	Close() error
+}
+
+// ConnectCloser opens connections that can be read from and closes whichever
+// connection it opened last.
+//
+// Deprecated: Implement Connector, whose connections close themselves, or
+// adapt a ConnectCloser with SharedClose.
+type ConnectCloser interface {
+	Connect() (Reader, error)
+	Close() error
+}
+
+// SharedClose adapts network to a Connector whose connections are closed by
+// calling network.Close.
+func SharedClose(network ConnectCloser) Connector {
+	return sharedClose{network}
+}
+
+type sharedClose struct {
+	network ConnectCloser
+}
+
+func (s sharedClose) Connect() (Conn, error) {
+	r, err := s.network.Connect()
+	if err != nil {
+		return nil, err
+	}
+	return sharedConn{Reader: Abandonable(r), network: s.network}, nil
+}
+
+// sharedConn closes its Reader by closing the network that opened it.  Its
+// Reader is always a ContextReader, as made by Abandonable.
+type sharedConn struct {
+	Reader
+	network ConnectCloser
+}
+
+func (c sharedConn) ReadContext(ctx context.Context) (*Message, error) {
+	return c.Reader.(ContextReader).ReadContext(ctx)
+}
+
+func (c sharedConn) Close() error {
+	return c.network.Close()
+}
+
+// Reader reads messages from a connection.
+type Reader interface {
+	Read() (*Message, error)
+}
+
+// Message is a single value read from a Reader.
+type Message struct {
+	Content string
+
+	// ID and Type identify the message for sources that label their
+	// messages, such as the id and event fields of a server-sent event.
+	ID   string `json:",omitempty"`
+	Type string `json:",omitempty"`
+}
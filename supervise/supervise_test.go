@@ -0,0 +1,46 @@
+package supervise_test
+
+import (
+	"testing"
+
+	"github.com/mstreet3/go-blogs/supervise"
+	"github.com/mstreet3/go-blogs/supervise/faults"
+)
+
+// legacyNetwork is a ConnectCloser that closes the reader it opened last.
+type legacyNetwork struct {
+	last   *faults.Reader
+	closes int
+}
+
+func (n *legacyNetwork) Connect() (supervise.Reader, error) {
+	n.last = faults.NewReader(1)
+	return n.last, nil
+}
+
+func (n *legacyNetwork) Close() error {
+	n.closes++
+	return n.last.Close()
+}
+
+func TestSharedCloseClosesThroughTheNetwork(t *testing.T) {
+	network := &legacyNetwork{}
+
+	conn, err := supervise.SharedClose(network).Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := conn.(supervise.ContextReader); !ok {
+		t.Errorf("connection %T is not a ContextReader", conn)
+	}
+
+	if err := conn.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if network.closes != 1 {
+		t.Errorf("closed the network %d times, want 1", network.closes)
+	}
+	if _, err := conn.Read(); err != faults.ErrClosed {
+		t.Errorf("read after Close returned %v, want %v", err, faults.ErrClosed)
+	}
+}
@@ -0,0 +1,284 @@
+package supervise
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrChildExited is the cause given to a supervised child that stopped on its
+// own without sending an error.
+var ErrChildExited = errors.New("supervise: child exited")
+
+// Worker starts a routine that runs until ctx is done and returns its done and
+// error channels.  Every worker in this package has this shape once its other
+// arguments are bound, including SupervisorContext itself.
+type Worker func(ctx context.Context) (<-chan struct{}, <-chan error)
+
+// ChildSpec describes a child of a Supervisor.
+type ChildSpec struct {
+	// Name identifies the child in errors and logs.
+	Name string
+
+	// Start starts the child.
+	Start Worker
+
+	// Health optionally judges the errors sent by the child and restarts
+	// it once the child is unhealthy.  A child is always restarted once it
+	// stops on its own.
+	Health HealthPolicy
+}
+
+// Strategy decides which children a Supervisor restarts when one fails.
+type Strategy int
+
+const (
+	// OneForOne restarts only the failed child.
+	OneForOne Strategy = iota
+
+	// OneForAll restarts every child.
+	OneForAll
+
+	// RestForOne restarts the failed child and every child started after
+	// it.
+	RestForOne
+)
+
+func (s Strategy) String() string {
+	switch s {
+	case OneForOne:
+		return "one-for-one"
+	case OneForAll:
+		return "one-for-all"
+	case RestForOne:
+		return "rest-for-one"
+	default:
+		return "unknown"
+	}
+}
+
+// ChildError is an error sent by, or the cause of the failure of, a
+// supervised child.
+type ChildError struct {
+	Name string
+	Err  error
+}
+
+func (e *ChildError) Error() string {
+	return fmt.Sprintf("child %s: %v", e.Name, e.Err)
+}
+
+func (e *ChildError) Unwrap() error {
+	return e.Err
+}
+
+// Supervisor supervises children until stop is closed.  See
+// SupervisorContext.
+func Supervisor(
+	stop <-chan struct{}, strategy Strategy, children []ChildSpec,
+	opts ...Option,
+) (<-chan struct{}, <-chan error) {
+	ctx, release := stopContext(stop)
+	done, errs := SupervisorContext(ctx, strategy, children, opts...)
+	releaseWhenDone(done, release)
+	return done, errs
+}
+
+// child is a running instance of a ChildSpec.
+type child struct {
+	spec    ChildSpec
+	cancel  context.CancelCauseFunc
+	stopped <-chan struct{}
+	started time.Time
+}
+
+// childExit reports the failure of a running child to its supervisor.
+type childExit struct {
+	index int
+	child *child
+	cause error
+}
+
+// SupervisorContext starts children in order and restarts them according to
+// strategy whenever one stops on its own or sends an error that its spec
+// deems unhealthy.  Errors sent by children are forwarded on the returned
+// error channel as *ChildError values.  Children are stopped in reverse order
+// once ctx is done or the supervisor gives up.
+func SupervisorContext(
+	ctx context.Context, strategy Strategy, children []ChildSpec,
+	opts ...Option,
+) (<-chan struct{}, <-chan error) {
+
+	o := newOptions(opts)
+	stop := ctx.Done()
+	done := make(chan struct{})
+	errs := make(chan error, 1)
+	exits := make(chan childExit)
+	running := make([]*child, len(children))
+
+	logger := o.log("supervisor")
+
+	dropErr := func(e error) {
+		o.dropErr("supervisor", e)
+	}
+
+	cleanup := func() {
+		sendCause(ctx, errs, dropErr)
+		close(errs)
+		close(done)
+	}
+
+	sendErr := func(e error) {
+		select {
+		case <-stop:
+			return
+		case errs <- e:
+		default:
+			dropErr(e)
+		}
+	}
+
+	// start starts the child at index i along with a watcher that forwards
+	// its errors and reports its failure.
+	start := func(i int) {
+		spec := children[i]
+		childCtx, cancel := context.WithCancelCause(ctx)
+		stopped := make(chan struct{})
+		c := &child{
+			spec:    spec,
+			cancel:  cancel,
+			stopped: stopped,
+			started: o.clock.Now(),
+		}
+		running[i] = c
+
+		logger.Info("starting child", "child", spec.Name)
+		childDone, childErrs := spec.Start(childCtx)
+
+		report := func(cause error) bool {
+			select {
+			case <-childCtx.Done():
+				return false
+			case exits <- childExit{index: i, child: c, cause: cause}:
+				return true
+			}
+		}
+
+		if spec.Health != nil {
+			spec.Health.Reset()
+		}
+
+		go func() {
+			defer close(stopped)
+
+			var (
+				last     error
+				reported bool
+			)
+
+			for e := range childErrs {
+				last = e
+				sendErr(&ChildError{Name: spec.Name, Err: e})
+
+				if !reported && spec.Health != nil &&
+					spec.Health.Failure(o.clock.Now(), e) {
+					reported = report(e)
+				}
+			}
+
+			<-childDone
+
+			if last == nil {
+				last = ErrChildExited
+			}
+
+			if !reported {
+				report(last)
+			}
+		}()
+	}
+
+	// stopRange stops the running children in [first, last) in reverse
+	// order and waits for each to finish.
+	stopRange := func(first, last int) {
+		for i := last - 1; i >= first; i-- {
+			if c := running[i]; c != nil {
+				logger.Info("stopping child", "child", c.spec.Name)
+				c.cancel(errStopped)
+				<-c.stopped
+				running[i] = nil
+			}
+		}
+	}
+
+	retries := &backoffState{backoff: o.backoff}
+	intensity := &restartIntensity{max: o.maxRestarts, window: o.window}
+
+	resetAfter := o.backoffReset
+	if resetAfter == 0 {
+		resetAfter = 10 * time.Second
+	}
+
+	go func() {
+		defer cleanup()
+		defer stopRange(0, len(children))
+
+		for i := range children {
+			start(i)
+		}
+
+		for {
+			select {
+			case <-stop:
+				logger.Info("received shutdown signal")
+				return
+			case exit := <-exits:
+				if running[exit.index] != exit.child {
+					continue
+				}
+
+				cause := &ChildError{Name: exit.child.spec.Name, Err: exit.cause}
+				logger.Warn("child failed; restarting",
+					"child", cause.Name, "strategy", strategy, "err", cause.Err)
+
+				if terminal := intensity.record(o.clock.Now(), cause); terminal != nil {
+					logger.Error("giving up", "err", terminal)
+					stopRange(0, len(children))
+					sendLast(errs, terminal, dropErr)
+					return
+				}
+
+				first, last := exit.index, exit.index+1
+				switch strategy {
+				case OneForAll:
+					first, last = 0, len(children)
+				case RestForOne:
+					last = len(children)
+				}
+
+				stopRange(first, last)
+
+				if o.clock.Now().Sub(exit.child.started) >= resetAfter {
+					retries.reset()
+				}
+
+				if o.backoff != nil {
+					timer := o.clock.NewTimer(retries.next())
+					select {
+					case <-stop:
+						timer.Stop()
+						return
+					case <-timer.C():
+					}
+				}
+
+				for i := first; i < last; i++ {
+					start(i)
+				}
+			}
+		}
+	}()
+
+	return done, errs
+}
@@ -0,0 +1,183 @@
+package supervise_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mstreet3/go-blogs/supervise"
+)
+
+// children counts how often each child is started.
+type children struct {
+	mu     sync.Mutex
+	starts map[string]int
+}
+
+func newChildren() *children {
+	return &children{starts: make(map[string]int)}
+}
+
+func (c *children) started(name string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.starts[name]
+}
+
+// start counts a start of the named child and returns how many there were.
+func (c *children) start(name string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.starts[name]++
+	return c.starts[name]
+}
+
+// worker returns a child that runs until its context is done, except that its
+// first run stops on its own after exitAfter when exitAfter is positive.
+func (c *children) worker(name string, exitAfter time.Duration) supervise.Worker {
+	return func(ctx context.Context) (<-chan struct{}, <-chan error) {
+		first := c.start(name) == 1
+		done := make(chan struct{})
+		errs := make(chan error)
+
+		go func() {
+			defer close(done)
+			defer close(errs)
+
+			if !first || exitAfter <= 0 {
+				<-ctx.Done()
+				return
+			}
+
+			select {
+			case <-ctx.Done():
+			case <-time.After(exitAfter):
+			}
+		}()
+
+		return done, errs
+	}
+}
+
+func TestSupervisorRestartsChildrenByStrategy(t *testing.T) {
+	tests := []struct {
+		strategy supervise.Strategy
+		want     map[string]int
+	}{
+		{supervise.OneForOne, map[string]int{"a": 1, "b": 2, "c": 1}},
+		{supervise.OneForAll, map[string]int{"a": 2, "b": 2, "c": 2}},
+		{supervise.RestForOne, map[string]int{"a": 1, "b": 2, "c": 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.strategy.String(), func(t *testing.T) {
+			c := newChildren()
+			specs := []supervise.ChildSpec{
+				{Name: "a", Start: c.worker("a", 0)},
+				{Name: "b", Start: c.worker("b", 5*time.Millisecond)},
+				{Name: "c", Start: c.worker("c", 0)},
+			}
+			stop := make(chan struct{})
+
+			done, errs := supervise.Supervisor(stop, tt.strategy, specs)
+			eventually(t, "the restarts", func() bool {
+				for name, want := range tt.want {
+					if c.started(name) != want {
+						return false
+					}
+				}
+				return true
+			})
+			close(stop)
+
+			for err := range errs {
+				t.Errorf("got error %v", err)
+			}
+			<-done
+
+			for name, want := range tt.want {
+				if got := c.started(name); got != want {
+					t.Errorf("started %s %d times, want %d", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestSupervisorRestartsAChildItsHealthPolicyFindsUnhealthy(t *testing.T) {
+	errBroken := errors.New("broken")
+	c := newChildren()
+	stop := make(chan struct{})
+
+	// The child keeps running after sending its error, so only its health
+	// policy can restart it.
+	worker := func(ctx context.Context) (<-chan struct{}, <-chan error) {
+		done := make(chan struct{})
+		errs := make(chan error, 1)
+		if c.start("a") == 1 {
+			errs <- errBroken
+		}
+
+		go func() {
+			defer close(done)
+			defer close(errs)
+			<-ctx.Done()
+		}()
+
+		return done, errs
+	}
+
+	done, errs := supervise.Supervisor(stop, supervise.OneForOne,
+		[]supervise.ChildSpec{{Name: "a", Start: worker,
+			Health: supervise.ConsecutiveErrors(1)}})
+
+	var ce *supervise.ChildError
+	if err := <-errs; !errors.As(err, &ce) || ce.Name != "a" || ce.Err != errBroken {
+		t.Errorf("got error %v, want child a: %v", err, errBroken)
+	}
+	eventually(t, "a to restart", func() bool { return c.started("a") == 2 })
+
+	close(stop)
+	<-done
+}
+
+func TestSupervisorGivesUpOnceItsRestartIntensityIsExceeded(t *testing.T) {
+	// The inner supervisor restarts a child that keeps exiting until it
+	// gives up, which makes the outer one give up too.
+	exiting := func(ctx context.Context) (<-chan struct{}, <-chan error) {
+		done := make(chan struct{})
+		errs := make(chan error)
+		go func() {
+			defer close(done)
+			close(errs)
+		}()
+		return done, errs
+	}
+	inner := func(ctx context.Context) (<-chan struct{}, <-chan error) {
+		return supervise.SupervisorContext(ctx, supervise.OneForOne,
+			[]supervise.ChildSpec{{Name: "x", Start: exiting}},
+			supervise.WithRestartIntensity(2, time.Minute))
+	}
+	stop := make(chan struct{})
+	defer close(stop)
+
+	done, errs := supervise.Supervisor(stop, supervise.OneForOne,
+		[]supervise.ChildSpec{{Name: "inner", Start: inner}},
+		supervise.WithRestartIntensity(1, time.Minute))
+
+	var last error
+	for err := range errs {
+		last = err
+	}
+	<-done
+
+	var ie *supervise.RestartIntensityError
+	if !errors.As(last, &ie) || ie.MaxRestarts != 1 {
+		t.Fatalf("got error %v, want the outer supervisor to give up", last)
+	}
+	if !errors.Is(last, supervise.ErrChildExited) {
+		t.Errorf("error %v does not match %v", last, supervise.ErrChildExited)
+	}
+}
@@ -0,0 +1,149 @@
+package supervise
+
+import (
+	"context"
+	"time"
+)
+
+// ReaderWard reads from conn on every tick of pulseInterval until stop is
+// closed.  See ReaderWardContext.
+func ReaderWard(
+	stop <-chan struct{}, conn Reader, pulseInterval time.Duration,
+	opts ...Option,
+) (<-chan struct{}, <-chan error) {
+	ctx, release := stopContext(stop)
+	done, errs := ReaderWardContext(ctx, conn, pulseInterval, opts...)
+	releaseWhenDone(done, release)
+	return done, errs
+}
+
+// ReaderWardContext reads from conn on every tick of pulseInterval and
+// forwards any read errors on its returned error channel until ctx is done.
+// Messages are sent to the channel given by WithMessages.  With
+// WithReadMode(Stream) the ward reads back to back instead and ticks only
+// pace its heartbeats.
+func ReaderWardContext(
+	ctx context.Context, conn Reader, pulseInterval time.Duration,
+	opts ...Option,
+) (<-chan struct{}, <-chan error) {
+	return readerWard(ctx, conn, pulseInterval, newOptions(opts))
+}
+
+// readerWard reads from conn on every tick and forwards any read errors on
+// its returned error channel.
+func readerWard(
+	ctx context.Context, conn Reader, pulseInterval time.Duration, o *options,
+) (<-chan struct{}, <-chan error) {
+
+	stop := ctx.Done()
+	done := make(chan struct{})
+	errs := make(chan error, 1)
+	ticker := o.clock.NewTicker(pulseInterval)
+
+	// A ward started by a steward shares the steward's outlet; otherwise
+	// it delivers its messages through an outlet of its own.
+	out, delivering := o.outlet, closedSignal
+	if out == nil && o.messages != nil {
+		out = newOutlet(o)
+		delivering = out.run(ctx)
+	}
+
+	logger := o.log("ward")
+
+	dropErr := func(e error) {
+		o.dropErr("ward", e)
+	}
+
+	// A read timeout, or a streaming ward being stopped, must be able to
+	// abandon a Read that never returns.
+	if o.readTimeout > 0 || o.readMode == Stream {
+		conn = Abandonable(conn)
+	}
+
+	cleanup := func() {
+		ticker.Stop()
+		<-delivering
+		sendCause(ctx, errs, dropErr)
+		close(errs)
+		close(done)
+	}
+
+	sendErr := func(e error) {
+		select {
+		case <-stop:
+			return
+		case errs <- e:
+		default:
+			dropErr(e)
+		}
+	}
+
+	// sendMsg delivers msg to the consumer and reports false if the ward
+	// was stopped first.  Without a consumer the message is logged.
+	sendMsg := func(msg *Message) bool {
+		if out == nil {
+			logger.Debug("read message", "content", msg.Content)
+			return true
+		}
+
+		return out.send(stop, msg)
+	}
+
+	// sendPulse signals that the ward is alive without blocking.
+	sendPulse := func() {
+		select {
+		case o.heartbeat <- o.clock.Now():
+		default:
+		}
+	}
+
+	// handle forwards the outcome of a read and reports false once the
+	// ward is stopped.
+	handle := func(msg *Message, err error) bool {
+		if ctx.Err() != nil {
+			return false
+		}
+
+		o.metrics.read(o.name, err)
+
+		if err != nil {
+			sendErr(err)
+			return true
+		}
+		return sendMsg(msg)
+	}
+
+	go func() {
+		defer cleanup()
+
+		if o.readMode == Stream {
+			streamReads(ctx, conn, o, ticker.C(), handle, sendPulse)
+			return
+		}
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-o.draining:
+				return
+			case <-ticker.C():
+				// A draining ward starts no new reads, even on a
+				// tick that arrived at the same time.
+				if isClosed(o.draining) {
+					return
+				}
+
+				msg, err := readWithin(ctx, conn,
+					o.readTimeout, o.clock)
+				if !handle(msg, err) {
+					return
+				}
+
+				sendPulse()
+			}
+		}
+	}()
+
+	return done, errs
+}
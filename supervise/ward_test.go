@@ -0,0 +1,72 @@
+package supervise_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mstreet3/go-blogs/supervise"
+	"github.com/mstreet3/go-blogs/supervise/faults"
+)
+
+func TestReaderWardForwardsReadErrors(t *testing.T) {
+	errBroken := errors.New("broken")
+	conn := faults.NewReader(1, faults.Script(faults.OK("a"),
+		faults.Fail(errBroken)))
+	stop := make(chan struct{})
+	out := make(chan *supervise.Message, 1)
+
+	done, errs := supervise.ReaderWard(stop, conn, time.Millisecond,
+		supervise.WithMessages(out))
+
+	if err := <-errs; err != errBroken {
+		t.Errorf("got error %v, want %v", err, errBroken)
+	}
+	if m := <-out; m.Content != "a" {
+		t.Errorf("read %q, want %q", m.Content, "a")
+	}
+
+	close(stop)
+	<-done
+	for range errs {
+	}
+}
+
+func TestReaderWardStopsWhenStopIsClosed(t *testing.T) {
+	stop := make(chan struct{})
+	done, errs := supervise.ReaderWard(stop, faults.NewReader(1),
+		time.Millisecond)
+
+	close(stop)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("ward did not stop")
+	}
+
+	for err := range errs {
+		t.Errorf("got error %v", err)
+	}
+}
+
+func TestReaderWardWaitsForASlowConsumer(t *testing.T) {
+	conn := faults.NewReader(1)
+	stop := make(chan struct{})
+	out := make(chan *supervise.Message)
+
+	done, _ := supervise.ReaderWard(stop, conn, time.Millisecond,
+		supervise.WithMessages(out))
+
+	// Nothing is read while the first message waits to be received.
+	time.Sleep(20 * time.Millisecond)
+	if got := conn.Reads(); got != 1 {
+		t.Errorf("read %d times before the first message was received, want 1", got)
+	}
+
+	if m := <-out; m.Content != "1" {
+		t.Errorf("read %q, want %q", m.Content, "1")
+	}
+
+	close(stop)
+	<-done
+}